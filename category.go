@@ -2,10 +2,14 @@ package main
 
 import (
 	"sort"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// Name of the category of commands added without one
+const DefaultCategoryName = "Other"
+
 type MessageCommandCategory struct {
 	Name        string
 	Description string
@@ -28,7 +32,113 @@ func (c *MessageCommandCategory) Embed() *discordgo.MessageEmbed {
 		}
 	}
 	return &discordgo.MessageEmbed{
-		Title: c.Name + " CATEGORY",
+		Title: strings.TrimSpace(c.Emoji + " " + c.Name + " CATEGORY"),
+		Description: c.Description,
 		Fields: f,
 	}
-}
\ No newline at end of file
+}
+
+// Emoji of the category for components, unicode or custom (<:name:id>, <a:name:id>)
+func (c *MessageCommandCategory) ComponentEmoji() discordgo.ComponentEmoji {
+	if m := customEmojiRegex.FindStringSubmatch(c.Emoji); m != nil {
+		return discordgo.ComponentEmoji{
+			Name: m[2],
+			ID: m[3],
+			Animated: m[1] == "a",
+		}
+	}
+	return discordgo.ComponentEmoji{Name: c.Emoji}
+}
+
+// Add categories to the router, a category with the same name is replaced
+// Commands already assigned to the replaced category are kept
+func (r *MessageCommandRouter) AddCategory(categories ...*MessageCommandCategory) {
+	r.categoryLock.Lock()
+	defer r.categoryLock.Unlock()
+
+	for _, c := range categories {
+		if i := r.categoryIndex(c.Name); i != -1 {
+			c.Item = append(r.Categories[i].Item, c.Item...)
+			r.Categories[i] = c
+		} else {
+			r.Categories = append(r.Categories, c)
+		}
+	}
+}
+
+// Get the category by its name, case insensitive
+func (r *MessageCommandRouter) GetCategory(name string) *MessageCommandCategory {
+	r.categoryLock.RLock()
+	defer r.categoryLock.RUnlock()
+
+	if i := r.categoryIndex(name); i != -1 {
+		return r.Categories[i]
+	}
+	return nil
+}
+
+// Copies of the categories, safe to read while commands are added or removed
+func (r *MessageCommandRouter) copyCategories() []*MessageCommandCategory {
+	r.categoryLock.RLock()
+	defer r.categoryLock.RUnlock()
+
+	categories := make([]*MessageCommandCategory, len(r.Categories))
+	for i, c := range r.Categories {
+		categories[i] = &MessageCommandCategory{
+			Name: 			c.Name,
+			Description: 	c.Description,
+			Emoji: 			c.Emoji,
+			Item: 			append([]*MessageCommand{}, c.Item...),
+		}
+	}
+	return categories
+}
+
+func (r *MessageCommandRouter) categoryIndex(name string) int {
+	for i, c := range r.Categories {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Put the command in its category, creating the category if it is not registered
+func (r *MessageCommandRouter) categorize(cmd *MessageCommand) {
+	r.categoryLock.Lock()
+	defer r.categoryLock.Unlock()
+
+	r.addToCategory(cmd)
+}
+
+// Same as categorize, categoryLock must be held
+func (r *MessageCommandRouter) addToCategory(cmd *MessageCommand) {
+	if cmd.Category == "" {
+		cmd.Category = DefaultCategoryName
+	}
+
+	i := r.categoryIndex(cmd.Category)
+	if i == -1 {
+		r.Categories = append(r.Categories, &MessageCommandCategory{Name: cmd.Category})
+		i = len(r.Categories) - 1
+	}
+	r.Categories[i].Item = append(r.Categories[i].Item, cmd)
+}
+
+// Take the command out of its category, the category is kept even if empty
+func (r *MessageCommandRouter) uncategorize(cmd *MessageCommand) {
+	r.categoryLock.Lock()
+	defer r.categoryLock.Unlock()
+
+	i := r.categoryIndex(cmd.Category)
+	if i == -1 {
+		return
+	}
+	c := r.Categories[i]
+	for j, item := range c.Item {
+		if item == cmd {
+			c.Item = append(c.Item[:j:j], c.Item[j+1:]...)
+			return
+		}
+	}
+}
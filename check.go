@@ -0,0 +1,201 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Return an error to prevent the command from running
+// The error is wrapped in a CheckFailedError and passed to the OnError hook of the router
+type MessageCommandCheck func(ctx *MessageCommandContext) error
+
+// Names of permissions shown to users
+var permissionNames = []struct {
+	perm 	int64
+	name 	string
+}{
+	{discordgo.PermissionCreateInstantInvite, "Create Invite"},
+	{discordgo.PermissionKickMembers, "Kick Members"},
+	{discordgo.PermissionBanMembers, "Ban Members"},
+	{discordgo.PermissionAdministrator, "Administrator"},
+	{discordgo.PermissionManageChannels, "Manage Channels"},
+	{discordgo.PermissionManageServer, "Manage Server"},
+	{discordgo.PermissionAddReactions, "Add Reactions"},
+	{discordgo.PermissionViewAuditLogs, "View Audit Log"},
+	{discordgo.PermissionViewChannel, "View Channel"},
+	{discordgo.PermissionSendMessages, "Send Messages"},
+	{discordgo.PermissionSendTTSMessages, "Send TTS Messages"},
+	{discordgo.PermissionManageMessages, "Manage Messages"},
+	{discordgo.PermissionEmbedLinks, "Embed Links"},
+	{discordgo.PermissionAttachFiles, "Attach Files"},
+	{discordgo.PermissionReadMessageHistory, "Read Message History"},
+	{discordgo.PermissionMentionEveryone, "Mention Everyone"},
+	{discordgo.PermissionUseExternalEmojis, "Use External Emojis"},
+	{discordgo.PermissionVoiceConnect, "Connect"},
+	{discordgo.PermissionVoiceSpeak, "Speak"},
+	{discordgo.PermissionVoiceMuteMembers, "Mute Members"},
+	{discordgo.PermissionVoiceDeafenMembers, "Deafen Members"},
+	{discordgo.PermissionVoiceMoveMembers, "Move Members"},
+	{discordgo.PermissionChangeNickname, "Change Nickname"},
+	{discordgo.PermissionManageNicknames, "Manage Nicknames"},
+	{discordgo.PermissionManageRoles, "Manage Roles"},
+	{discordgo.PermissionManageWebhooks, "Manage Webhooks"},
+	{discordgo.PermissionManageEmojis, "Manage Emojis"},
+}
+
+// Human readable list of permissions
+func permissionString(perm int64) string {
+	names := []string{}
+	for _, p := range permissionNames {
+		if perm&p.perm != 0 {
+			names = append(names, p.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Get the permissions in perm that the user does not have in the channel
+func missingPermissions(s *discordgo.Session, channelID string, userID string, perm int64) (int64, error) {
+	p, err := s.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		return 0, err
+	}
+	return perm &^ p, nil
+}
+
+// Permissions of users in the channel of an invocation, so help and suggestions do not look them up for every command
+type permissionCache struct {
+	sync.Mutex
+	lookups 	map[string]permissionLookup
+}
+
+type permissionLookup struct {
+	perm 	int64
+	err 	error
+}
+
+// Get the permissions of the user in the channel of m
+// The author's are computed from the member of m when the server is in the state, without any request
+func channelPermissions(s *discordgo.Session, m *discordgo.Message, userID string) (int64, error) {
+	if m.Member != nil && m.Author != nil && m.Author.ID == userID {
+		if p, err := s.State.MessagePermissions(m); err == nil {
+			return p, nil
+		}
+	}
+	return s.UserChannelPermissions(userID, m.ChannelID)
+}
+
+// Get the permissions of the user in the channel of ctx, looked up once per invocation
+func (ctx *MessageCommandContext) channelPermissions(userID string) (int64, error) {
+	c := ctx.permissions
+	if c == nil {
+		return channelPermissions(ctx.Session, ctx.Message, userID)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if l, ok := c.lookups[userID]; ok {
+		return l.perm, l.err
+	}
+	if c.lookups == nil {
+		c.lookups = make(map[string]permissionLookup)
+	}
+	perm, err := channelPermissions(ctx.Session, ctx.Message, userID)
+	c.lookups[userID] = permissionLookup{perm, err}
+	return perm, err
+}
+
+// Check if the channel is marked as NSFW
+func isNSFWChannel(s *discordgo.Session, channelID string) bool {
+	c, err := s.State.Channel(channelID)
+	if err != nil {
+		if c, err = s.Channel(channelID); err != nil {
+			return false
+		}
+	}
+	return c.NSFW
+}
+
+// Run the built-in checks then the custom checks of the command
+// Failed checks are returned as a CheckFailedError, errors looking up permissions as is
+func (cmd *MessageCommand) runChecks(ctx *MessageCommandContext) error {
+	s, m := ctx.Session, ctx.Message
+	fail := func(err error) error {
+		return &CheckFailedError{ctx.Command, err}
+	}
+
+	if cmd.GuildOnly && m.GuildID == "" {
+		return fail(ErrGuildOnly)
+	}
+	if cmd.DMOnly && m.GuildID != "" {
+		return fail(ErrDMOnly)
+	}
+	if cmd.OwnerOnly && !ctx.Router.IsOwner(m.Author.ID) {
+		return fail(ErrOwnerOnly)
+	}
+	if cmd.NSFWOnly && m.GuildID != "" && !isNSFWChannel(s, m.ChannelID) {
+		return fail(ErrNSFWOnly)
+	}
+
+	// Permissions only exist in servers
+	if m.GuildID != "" {
+		if cmd.Permissions != 0 {
+			p, err := ctx.channelPermissions(m.Author.ID)
+			if err != nil {
+				return err
+			}
+			if missing := cmd.Permissions &^ p; missing != 0 {
+				return fail(&MissingPermissionsError{false, missing})
+			}
+		}
+		if cmd.BotPermissions != 0 && s.State.User != nil {
+			p, err := ctx.channelPermissions(s.State.User.ID)
+			if err != nil {
+				return err
+			}
+			if missing := cmd.BotPermissions &^ p; missing != 0 {
+				return fail(&MissingPermissionsError{true, missing})
+			}
+		}
+	}
+
+	for _, check := range cmd.Checks {
+		if err := check(ctx); err != nil {
+			return fail(err)
+		}
+	}
+
+	return nil
+}
+
+// Check if the command can run in ctx
+// Checks of the parents of the command are run first
+// Return a CheckFailedError when a check fails
+// Other errors, like failing to get the permissions of the user, are returned as is
+func (r *MessageCommandRouter) CanRun(ctx *MessageCommandContext, cmd *MessageCommand) error {
+	path := []*MessageCommand{}
+	for c := cmd; c != nil; c = c.Parent {
+		path = append([]*MessageCommand{c}, path...)
+	}
+
+	// Checks see the command being checked
+	checkCtx := *ctx
+	checkCtx.Command = cmd
+
+	for _, c := range path {
+		if err := c.runChecks(&checkCtx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check if the command is enabled where ctx was invoked and its checks pass
+// Used to hide commands the user cannot use from help and suggestions
+func (r *MessageCommandRouter) canUse(ctx *MessageCommandContext, cmd *MessageCommand) bool {
+	return r.checkOverrides(cmd, ctx.Message) == nil && r.CanRun(ctx, cmd) == nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCanRun(t *testing.T) {
+	errClosed := errors.New("the shop is closed")
+	r := NewMessageCommandRouter(nil)
+
+	shop := NewMessageCommand("shop", "", nil, true, nil, nil, nil)
+	shop.Checks = []MessageCommandCheck{func(ctx *MessageCommandContext) error {
+		if ctx.Message.ChannelID == "closed" {
+			return errClosed
+		}
+		return nil
+	}}
+	buy := NewMessageCommand("buy", "", nil, true, nil, nil, nil)
+	buy.Parent = shop
+	buy.GuildOnly = true
+
+	tests := []struct {
+		name 	string
+		m 		*discordgo.Message
+		want 	error
+	}{
+		{"passes", &discordgo.Message{GuildID: "1", ChannelID: "open", Author: &discordgo.User{ID: "1"}}, nil},
+		{"check of the parent", &discordgo.Message{GuildID: "1", ChannelID: "closed", Author: &discordgo.User{ID: "1"}}, errClosed},
+		{"built-in check", &discordgo.Message{ChannelID: "open", Author: &discordgo.User{ID: "1"}}, ErrGuildOnly},
+	}
+
+	for _, tt := range tests {
+		ctx := &MessageCommandContext{Session: &discordgo.Session{}, Message: tt.m, Router: r}
+		err := r.CanRun(ctx, buy)
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		// Failed checks are reported for the checked command
+		var check *CheckFailedError
+		if !errors.As(err, &check) || check.Command != buy || !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want a CheckFailedError of buy with %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestChannelPermissions(t *testing.T) {
+	state := discordgo.NewState()
+	state.User = &discordgo.User{ID: "bot"}
+	everyone := &discordgo.Role{ID: "1", Permissions: discordgo.PermissionViewChannel | discordgo.PermissionSendMessages}
+	mod := &discordgo.Role{ID: "2", Permissions: discordgo.PermissionManageMessages}
+	err := state.GuildAdd(&discordgo.Guild{
+		ID: 		"1",
+		OwnerID: 	"owner",
+		Roles: 		[]*discordgo.Role{everyone, mod},
+		Channels: 	[]*discordgo.Channel{{ID: "10", GuildID: "1"}},
+		Members: 	[]*discordgo.Member{{GuildID: "1", User: &discordgo.User{ID: "bot"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	purge := NewMessageCommand("purge", "", nil, true, nil, nil, nil)
+	purge.Permissions = discordgo.PermissionManageMessages
+	purge.BotPermissions = discordgo.PermissionManageMessages
+
+	r := NewMessageCommandRouter(nil)
+	ctx := &MessageCommandContext{
+		Session: 		&discordgo.Session{State: state},
+		Message: 		&discordgo.Message{
+			GuildID: 	"1",
+			ChannelID: 	"10",
+			Author: 	&discordgo.User{ID: "1"},
+			Member: 	&discordgo.Member{Roles: []string{"2"}},
+		},
+		Router: 		r,
+		permissions: 	&permissionCache{},
+	}
+
+	// The author has the permission from the member of the message, the bot lacks it
+	var perm *MissingPermissionsError
+	if err := r.CanRun(ctx, purge); !errors.As(err, &perm) || !perm.Bot || perm.Missing != discordgo.PermissionManageMessages {
+		t.Fatalf("error = %v, want the bot to miss Manage Messages", err)
+	}
+
+	// Later checks of the invocation reuse the permissions
+	everyone.Permissions |= discordgo.PermissionManageMessages
+	if err := r.CanRun(ctx, purge); !errors.As(err, &perm) || !perm.Bot {
+		t.Errorf("error = %v, want the permissions looked up before", err)
+	}
+	if len(ctx.permissions.lookups) != 2 {
+		t.Errorf("%d users looked up, want 2", len(ctx.permissions.lookups))
+	}
+}
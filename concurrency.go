@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Used when MaxWaiting of a MaxConcurrency is 0
+const defaultMaxConcurrencyWaiting = 10
+
+// Used when WaitTimeout of a MaxConcurrency is 0
+const defaultMaxConcurrencyWaitTimeout = time.Minute
+
+// Allow at most Limit uses of a command to run at once in each bucket
+type MaxConcurrency struct {
+	// Must be positive
+	Limit 		int
+	Bucket 		CommandBucket
+
+	// Wait for a use to finish instead of returning a MaxConcurrencyError
+	// Waiting uses hold a worker of the executor, the timeout of the command starts after they get a slot
+	Wait 		bool
+
+	// Max number of uses waiting in each bucket, 10 if 0
+	// Uses beyond it get a MaxConcurrencyError right away
+	MaxWaiting 	int
+
+	// How long a use waits before getting a MaxConcurrencyError, 1 minute if 0
+	WaitTimeout time.Duration
+}
+
+// Return an error if the limit can never let the command run or has no bucket
+func (c *MaxConcurrency) validate() error {
+	if c.Limit <= 0 || c.MaxWaiting < 0 || c.WaitTimeout < 0 {
+		return ErrInvalidMaxConcurrency
+	}
+	if !c.Bucket.valid() {
+		return ErrUnknownBucket
+	}
+	return nil
+}
+
+// Max number of uses running or waiting in a bucket
+func (c *MaxConcurrency) maxUses() int {
+	if !c.Wait {
+		return c.Limit
+	}
+	if c.MaxWaiting == 0 {
+		return c.Limit + defaultMaxConcurrencyWaiting
+	}
+	return c.Limit + c.MaxWaiting
+}
+
+func (c *MaxConcurrency) waitTimeout() time.Duration {
+	if c.WaitTimeout == 0 {
+		return defaultMaxConcurrencyWaitTimeout
+	}
+	return c.WaitTimeout
+}
+
+type concurrencyKey struct {
+	cmd 	*MessageCommand
+	bucket 	string
+}
+
+// Running uses of a bucket, slots holds one value per use
+type concurrencySlots struct {
+	slots 	chan struct{}
+	// Uses running or waiting, the slots are removed when it drops to 0
+	refs 	int
+}
+
+// Track running uses of every command of a router
+type concurrencyMapping struct {
+	sync.Mutex
+	buckets 	map[concurrencyKey]*concurrencySlots
+}
+
+// A use of a command counted in its bucket, running once it holds a slot
+// The zero value is a use of a command without limit
+type concurrencyUse struct {
+	cm 			*concurrencyMapping
+	key 		concurrencyKey
+	bucket 		*concurrencySlots
+	acquired 	bool
+}
+
+// Count a use in the bucket of the message, without taking a slot yet
+// Return false if the bucket already has as many uses running or waiting as the limit allows
+func (cm *concurrencyMapping) reserve(cmd *MessageCommand, m *discordgo.Message) (*concurrencyUse, bool) {
+	c := cmd.MaxConcurrency
+	key := concurrencyKey{cmd, c.Bucket.key(m)}
+
+	cm.Lock()
+	defer cm.Unlock()
+
+	if cm.buckets == nil {
+		cm.buckets = make(map[concurrencyKey]*concurrencySlots)
+	}
+	b, ok := cm.buckets[key]
+	if !ok {
+		b = &concurrencySlots{slots: make(chan struct{}, c.Limit)}
+		cm.buckets[key] = b
+	}
+	if b.refs >= c.maxUses() {
+		return nil, false
+	}
+	b.refs++
+	return &concurrencyUse{cm: cm, key: key, bucket: b}, true
+}
+
+func (cm *concurrencyMapping) unref(key concurrencyKey) {
+	cm.Lock()
+	defer cm.Unlock()
+
+	if b := cm.buckets[key]; b != nil {
+		b.refs--
+		if b.refs == 0 {
+			delete(cm.buckets, key)
+		}
+	}
+}
+
+// Take a slot of the bucket, waiting until ctx is done if every slot is taken
+// There is always a free slot for commands that do not wait, see reserve
+func (u *concurrencyUse) wait(ctx context.Context) error {
+	if u.bucket == nil || u.acquired {
+		return nil
+	}
+	select {
+	case u.bucket.slots <- struct{}{}:
+		u.acquired = true
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Free the slot if the use holds one and stop counting the use, call it once
+func (u *concurrencyUse) release() {
+	if u.bucket == nil {
+		return
+	}
+	if u.acquired {
+		<-u.bucket.slots
+	}
+	u.cm.unref(u.key)
+}
+
+// Count a use of the command in its bucket if it has a MaxConcurrency
+// Return a MaxConcurrencyError if the bucket has no room left for it, see MaxConcurrency.MaxWaiting
+// Call waitConcurrency before running the command and release the use once it is done
+func (r *MessageCommandRouter) reserveConcurrency(ctx *MessageCommandContext, cmd *MessageCommand) (*concurrencyUse, error) {
+	if cmd.MaxConcurrency == nil {
+		return &concurrencyUse{}, nil
+	}
+	// Changed after the command was added
+	if err := cmd.MaxConcurrency.validate(); err != nil {
+		return nil, &InvalidCommandError{cmd, err}
+	}
+	use, ok := r.concurrency.reserve(cmd, ctx.Message)
+	if !ok {
+		return nil, &MaxConcurrencyError{cmd, cmd.MaxConcurrency.Limit, cmd.MaxConcurrency.Bucket}
+	}
+	return use, nil
+}
+
+// Take the slot of a reserved use, waiting at most WaitTimeout for it
+// Return a MaxConcurrencyError if no slot freed in time, or the error of the root context if it is done
+func (r *MessageCommandRouter) waitConcurrency(cmd *MessageCommand, use *concurrencyUse) error {
+	if cmd.MaxConcurrency == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(r.rootContext(), cmd.MaxConcurrency.waitTimeout())
+	defer cancel()
+
+	err := use.wait(ctx)
+	if errors.Is(err, context.DeadlineExceeded) && r.rootContext().Err() == nil {
+		return &MaxConcurrencyError{cmd, cmd.MaxConcurrency.Limit, cmd.MaxConcurrency.Bucket}
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func concurrencyContext(r *MessageCommandRouter, userID string) *MessageCommandContext {
+	return &MessageCommandContext{
+		Router: 	r,
+		Message: 	&discordgo.Message{GuildID: "1", Author: &discordgo.User{ID: userID}},
+	}
+}
+
+// Reserve a use and take its slot
+func runConcurrency(t *testing.T, r *MessageCommandRouter, ctx *MessageCommandContext, cmd *MessageCommand) *concurrencyUse {
+	use, err := r.reserveConcurrency(ctx, cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.waitConcurrency(cmd, use); err != nil {
+		t.Fatal(err)
+	}
+	return use
+}
+
+func TestReserveConcurrency(t *testing.T) {
+	r := NewMessageCommandRouter(nil)
+	cmd := &MessageCommand{Name: "play", MaxConcurrency: &MaxConcurrency{Limit: 1}}
+	ctx := concurrencyContext(r, "1")
+
+	use := runConcurrency(t, r, ctx, cmd)
+	var limited *MaxConcurrencyError
+	if _, err := r.reserveConcurrency(ctx, cmd); !errors.As(err, &limited) {
+		t.Fatalf("second use error = %v, want a MaxConcurrencyError", err)
+	}
+
+	// The zero bucket is per user
+	runConcurrency(t, r, concurrencyContext(r, "2"), cmd).release()
+
+	use.release()
+	runConcurrency(t, r, ctx, cmd).release()
+
+	if len(r.concurrency.buckets) != 0 {
+		t.Errorf("%d buckets left after every use ended, want 0", len(r.concurrency.buckets))
+	}
+}
+
+func TestWaitConcurrency(t *testing.T) {
+	r := NewMessageCommandRouter(nil)
+	cmd := &MessageCommand{Name: "play", MaxConcurrency: &MaxConcurrency{
+		Limit: 			1,
+		Wait: 			true,
+		MaxWaiting: 	1,
+		WaitTimeout: 	20 * time.Millisecond,
+	}}
+	ctx := concurrencyContext(r, "1")
+	running := runConcurrency(t, r, ctx, cmd)
+
+	// One use can wait, the next one is rejected right away
+	waiting, err := r.reserveConcurrency(ctx, cmd)
+	if err != nil {
+		t.Fatalf("waiting use error = %v", err)
+	}
+	var limited *MaxConcurrencyError
+	if _, err := r.reserveConcurrency(ctx, cmd); !errors.As(err, &limited) {
+		t.Fatalf("use beyond MaxWaiting error = %v, want a MaxConcurrencyError", err)
+	}
+
+	// Waiting ends after WaitTimeout
+	if err := r.waitConcurrency(cmd, waiting); !errors.As(err, &limited) {
+		t.Fatalf("use waiting too long error = %v, want a MaxConcurrencyError", err)
+	}
+
+	// A slot freed while waiting is taken
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		running.release()
+	}()
+	if err := r.waitConcurrency(cmd, waiting); err != nil {
+		t.Fatalf("waiting use error = %v", err)
+	}
+	waiting.release()
+
+	// Waiting stops when the bot shuts down
+	root, cancel := context.WithCancel(context.Background())
+	r.RootContext = root
+	running = runConcurrency(t, r, ctx, cmd)
+	waiting, _ = r.reserveConcurrency(ctx, cmd)
+	cancel()
+	if err := r.waitConcurrency(cmd, waiting); !errors.Is(err, context.Canceled) {
+		t.Fatalf("use waiting during shutdown error = %v, want %v", err, context.Canceled)
+	}
+	waiting.release()
+	running.release()
+
+	if len(r.concurrency.buckets) != 0 {
+		t.Errorf("%d buckets left after every use ended, want 0", len(r.concurrency.buckets))
+	}
+}
+
+func TestMaxConcurrencyValidate(t *testing.T) {
+	tests := []struct {
+		limit 	*MaxConcurrency
+		err 	error
+	}{
+		{&MaxConcurrency{Limit: 1}, nil},
+		{&MaxConcurrency{Limit: 2, Bucket: CommandBucketGuild, Wait: true, MaxWaiting: 5}, nil},
+		{&MaxConcurrency{Limit: 0}, ErrInvalidMaxConcurrency},
+		{&MaxConcurrency{Limit: -1}, ErrInvalidMaxConcurrency},
+		{&MaxConcurrency{Limit: 1, MaxWaiting: -1}, ErrInvalidMaxConcurrency},
+		{&MaxConcurrency{Limit: 1, WaitTimeout: -time.Second}, ErrInvalidMaxConcurrency},
+		{&MaxConcurrency{Limit: 1, Bucket: 42}, ErrUnknownBucket},
+	}
+
+	for _, tt := range tests {
+		cmd := &MessageCommand{Name: "play", MaxConcurrency: tt.limit}
+		if err := NewMessageCommandRouter(nil).AddCommand(cmd); !errors.Is(err, tt.err) {
+			t.Errorf("AddCommand with %+v = %v, want %v", tt.limit, err, tt.err)
+		}
+	}
+
+	// Limits changed after the command was added never reach make
+	r := NewMessageCommandRouter(nil)
+	cmd := &MessageCommand{Name: "play", MaxConcurrency: &MaxConcurrency{Limit: -1}}
+	if _, err := r.reserveConcurrency(concurrencyContext(r, "1"), cmd); !errors.Is(err, ErrInvalidMaxConcurrency) {
+		t.Errorf("reserveConcurrency with a negative limit = %v, want %v", err, ErrInvalidMaxConcurrency)
+	}
+}
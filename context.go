@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/bwmarrin/discordgo"
@@ -18,11 +19,43 @@ type MessageCommandContext struct {
 	// Raw arguments that user passed in as strings
 	RawArgs 		[]string
 
+	// Raw values of the flags that user passed in, mapped by flag name
+	RawFlags 		map[string]string
+
 	// Map name of params to the value
 	// Remember to assert type because default type is interface{}
 	ConvertedArgs	map[string]interface{}
 
 	Command 		*MessageCommand
+
+	// Cancelled when the bot shuts down or the command times out, see Context
+	context 		context.Context
+
+	// Responses of the context, nil if the router does not track them
+	invocation 		*invocation
+
+	// Run after the handler returns, outside of the executor, see Detach
+	detached 		[]MessageCommandHandler
+
+	// Permissions in the channel, shared by the copies of the context made for checks
+	permissions 	*permissionCache
+}
+
+// Context of the command, done when the bot shuts down or the command times out
+// Pass it to long operations, like EventWaiter.WaitForContext and Widget.DeployContext
+func (ctx *MessageCommandContext) Context() context.Context {
+	if ctx.context == nil {
+		return context.Background()
+	}
+	return ctx.context
+}
+
+// Run h in its own goroutine once the handler returns, so long waits do not hold a worker of the executor
+// Use it for widgets, paginators and EventWaiter waits started by the handler
+// The command keeps its context and its concurrency slot until h returns, errors and panics of h are handled like the ones of the handler
+// Must be called by the handler itself before it returns
+func (ctx *MessageCommandContext) Detach(h MessageCommandHandler) {
+	ctx.detached = append(ctx.detached, h)
 }
 
 func (ctx *MessageCommandContext) RespondText(s... interface{}) (*discordgo.Message, error) {
@@ -34,7 +67,7 @@ func (ctx *MessageCommandContext) RespondText(s... interface{}) (*discordgo.Mess
 			GuildID: ctx.Message.GuildID,
 		},
 	}
-	return ctx.Session.ChannelMessageSendComplex(ctx.Message.ChannelID, msg)
+	return ctx.send(msg)
 }
 
 // Reply to the message that invoked the command
@@ -44,17 +77,19 @@ func (ctx *MessageCommandContext) Respond(d *discordgo.MessageSend) (*discordgo.
 		ChannelID: ctx.Message.ChannelID,
 		GuildID: ctx.Message.GuildID,
 	}
-	m, err := ctx.Session.ChannelMessageSendComplex(ctx.Message.ChannelID, d)
+	m, err := ctx.send(d)
 	return m, err
 }
 
 // Send the message to the channel invoked the command
 func (ctx *MessageCommandContext) Send(d *discordgo.MessageSend) (*discordgo.Message, error) {
-	m, err := ctx.Session.ChannelMessageSendComplex(ctx.Message.ChannelID, d)
+	m, err := ctx.send(d)
 	return m, err
 }
 
 func (ctx *MessageCommandContext) SendText(s string) (*discordgo.Message, error) {
-	m, err := ctx.Session.ChannelMessageSend(ctx.Message.ChannelID, s)
+	m, err := ctx.send(&discordgo.MessageSend{
+		Content: s,
+	})
 	return m, err
 }
\ No newline at end of file
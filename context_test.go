@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func commandMessage(content string) *discordgo.Message {
+	return &discordgo.Message{
+		ID: 		"1",
+		ChannelID: 	"10",
+		Content: 	content,
+		Author: 	&discordgo.User{ID: "1"},
+	}
+}
+
+func TestDetachFreesTheWorker(t *testing.T) {
+	r := NewMessageCommandRouter([]string{"!"})
+	r.Executor = NewExecutor(1, 1, ExecutorOverflowBlock)
+	errs := make(chan error, 10)
+	r.OnError = func(ctx *MessageCommandContext, err error) {
+		errs <- err
+	}
+
+	block := make(chan struct{})
+	detached := make(chan *MessageCommandContext, 1)
+	ran := make(chan struct{}, 1)
+	wait := NewMessageCommand("wait", "", nil, false, nil, nil, func(ctx *MessageCommandContext) error {
+		ctx.Detach(func(ctx *MessageCommandContext) error {
+			<-block
+			detached <- ctx
+			return nil
+		})
+		return nil
+	})
+	ping := NewMessageCommand("ping", "", nil, false, nil, nil, func(ctx *MessageCommandContext) error {
+		ran <- struct{}{}
+		return nil
+	})
+	for _, cmd := range []*MessageCommand{wait, ping} {
+		if err := r.AddCommand(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Queued behind wait, only runs if wait frees the only worker
+	r.dispatch(nil, commandMessage("!wait"))
+	r.dispatch(nil, commandMessage("!ping"))
+	select {
+	case <-ran:
+	case err := <-errs:
+		t.Fatalf("ping failed while wait is detached: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("ping did not run, the detached handler holds the worker")
+	}
+
+	close(block)
+	select {
+	case ctx := <-detached:
+		// Cancelled once the detached handler returns
+		select {
+		case <-ctx.Context().Done():
+		case <-time.After(time.Second):
+			t.Fatal("context is not cancelled after the detached handler")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("detached handler did not run")
+	}
+}
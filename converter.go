@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Convert raw arguments to the value of a param type
+// Register it on a router to support a custom MessageCommandParamType
+type ArgumentConverter interface {
+	// Convert the raw argument, the value is put in ConvertedArgs
+	Convert(ctx *MessageCommandContext, arg string) (interface{}, error)
+
+	// Name of the type shown to users, like "int"
+	Name() string
+
+	// Formats accepted by Convert, shown to users when conversion fails
+	Help() string
+}
+
+// ArgumentConverter made of a function
+type ConverterFunc struct {
+	TypeName 	string
+	HelpText 	string
+	Func 		func(ctx *MessageCommandContext, arg string) (interface{}, error)
+}
+
+func (c *ConverterFunc) Convert(ctx *MessageCommandContext, arg string) (interface{}, error) {
+	return c.Func(ctx, arg)
+}
+
+func (c *ConverterFunc) Name() string {
+	return c.TypeName
+}
+
+func (c *ConverterFunc) Help() string {
+	return c.HelpText
+}
+
+// Map param types to their converters
+type ConverterRegistry struct {
+	// To provide thread safe access endpoints
+	sync.RWMutex
+
+	// Looked up for types without converter in this registry, nil for none
+	Parent 		*ConverterRegistry
+
+	converters 	map[MessageCommandParamType]ArgumentConverter
+}
+
+func NewConverterRegistry(parent *ConverterRegistry) *ConverterRegistry {
+	return &ConverterRegistry{
+		Parent: 	parent,
+		converters: make(map[MessageCommandParamType]ArgumentConverter),
+	}
+}
+
+// Use the converter for the type, replacing the previous one
+func (cr *ConverterRegistry) Register(t MessageCommandParamType, c ArgumentConverter) {
+	cr.Lock()
+	defer cr.Unlock()
+
+	if cr.converters == nil {
+		cr.converters = make(map[MessageCommandParamType]ArgumentConverter)
+	}
+	cr.converters[t] = c
+}
+
+// Get the converter of the type, from the parent if not registered here
+// Return nil if there is none
+func (cr *ConverterRegistry) Get(t MessageCommandParamType) ArgumentConverter {
+	cr.RLock()
+	c, ok := cr.converters[t]
+	cr.RUnlock()
+
+	if ok {
+		return c
+	}
+	if cr.Parent != nil {
+		return cr.Parent.Get(t)
+	}
+	return nil
+}
+
+// Name of the type shown to users, "value" if the type has no converter
+func (cr *ConverterRegistry) typeName(t MessageCommandParamType) string {
+	if c := cr.Get(t); c != nil {
+		return c.Name()
+	}
+	return "value"
+}
+
+// Convert the argument with the converter of the type
+// Return a ConversionError for param if it fails
+func (cr *ConverterRegistry) convert(ctx *MessageCommandContext, param string, arg string, t MessageCommandParamType) (interface{}, error) {
+	c := cr.Get(t)
+	if c == nil {
+		return nil, &ConversionError{param, arg, ErrUnknownParamType, ""}
+	}
+	res, err := c.Convert(ctx, arg)
+	if err != nil {
+		return nil, &ConversionError{param, arg, err, c.Help()}
+	}
+	return res, nil
+}
+
+// Converters of the built-in param types, the parent of the registry of every router
+// Initialized with the package variables so commands built in init functions can use it
+var DefaultConverters = newDefaultConverters()
+
+func newDefaultConverters() *ConverterRegistry {
+	cr := NewConverterRegistry(nil)
+	cr.Register(MessageCommandParamTypeString, &ConverterFunc{
+		TypeName: 	"string",
+		HelpText: 	"any text, quoted if it contains spaces",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			return arg, nil
+		},
+	})
+	cr.Register(MessageCommandParamTypeInteger, &ConverterFunc{
+		TypeName: 	"int",
+		HelpText: 	"a whole number like 42",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			res, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%s is not integer", arg)
+			}
+			return res, nil
+		},
+	})
+	cr.Register(MessageCommandParamTypeBoolean, &ConverterFunc{
+		TypeName: 	"boolean",
+		HelpText: 	"true or false",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			res, err := strconv.ParseBool(arg)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %s to boolean", arg)
+			}
+			return res, nil
+		},
+	})
+	cr.Register(MessageCommandParamTypeUser, &ConverterFunc{
+		TypeName: 	"user",
+		HelpText: 	"a mention, an ID or name#tag",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			return userConverter(ctx.Session, arg)
+		},
+	})
+	cr.Register(MessageCommandParamTypeRole, &ConverterFunc{
+		TypeName: 	"role",
+		HelpText: 	"a mention or an ID",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			return roleConverter(ctx.Session, ctx.Message.GuildID, arg)
+		},
+	})
+	cr.Register(MessageCommandParamTypeChannel, &ConverterFunc{
+		TypeName: 	"channel",
+		HelpText: 	"a mention or an ID of a channel of this server",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			return channelConverter(ctx.Session, ctx.Message.GuildID, arg)
+		},
+	})
+	cr.Register(MessageCommandParamTypeFloat, &ConverterFunc{
+		TypeName: 	"float",
+		HelpText: 	"a number like 4.2",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			res, err := strconv.ParseFloat(arg, 64)
+			if err != nil || math.IsNaN(res) || math.IsInf(res, 0) {
+				return nil, fmt.Errorf("%s is not a number", arg)
+			}
+			return res, nil
+		},
+	})
+	cr.Register(MessageCommandParamTypeDuration, &ConverterFunc{
+		TypeName: 	"duration",
+		HelpText: 	"a duration like 90s, 1h30m or 1d2h",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			return parseDuration(arg)
+		},
+	})
+	cr.Register(MessageCommandParamTypeTimestamp, &ConverterFunc{
+		TypeName: 	"timestamp",
+		HelpText: 	"a date like 2022-01-31 18:00 or 2022-01-31T18:00+02:00, unix seconds or a Discord timestamp <t:1643652000>",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			return parseTimestamp(arg)
+		},
+	})
+	cr.Register(MessageCommandParamTypeMember, &ConverterFunc{
+		TypeName: 	"member",
+		HelpText: 	"a mention, an ID, name#tag, a username or a nickname of a member of this server",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			return memberConverter(ctx.Session, ctx.Message.GuildID, arg)
+		},
+	})
+	cr.Register(MessageCommandParamTypeEmoji, &ConverterFunc{
+		TypeName: 	"emoji",
+		HelpText: 	"an emoji, a custom emoji or the name of an emoji of this server",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			return emojiConverter(ctx.Session, ctx.Message.GuildID, arg)
+		},
+	})
+	cr.Register(MessageCommandParamTypeMessage, &ConverterFunc{
+		TypeName: 	"message",
+		HelpText: 	"a message link, channelID-messageID or the ID of a message of this channel",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			return messageConverter(ctx.Session, ctx.Message, arg)
+		},
+	})
+	return cr
+}
+
+// Register a converter on the router only, see ConverterRegistry.Register
+func (r *MessageCommandRouter) RegisterConverter(t MessageCommandParamType, c ArgumentConverter) {
+	if r.Converters == nil {
+		r.Converters = NewConverterRegistry(DefaultConverters)
+	}
+	r.Converters.Register(t, c)
+}
+
+func (r *MessageCommandRouter) converters() *ConverterRegistry {
+	if r.Converters == nil {
+		return DefaultConverters
+	}
+	return r.Converters
+}
+
+// Embed of the command showing the type names of the converters of the router
+func (r *MessageCommandRouter) CommandEmbed(cmd *MessageCommand) *discordgo.MessageEmbed {
+	return cmd.embed(r.converters())
+}
+
+// Converters of the router of ctx, DefaultConverters if there is no router
+func (ctx *MessageCommandContext) converters() *ConverterRegistry {
+	if ctx.Router == nil {
+		return DefaultConverters
+	}
+	return ctx.Router.converters()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Who shares a limit of a command
+// The zero value is the same as CommandBucketUser
+type CommandBucket uint8
+
+// Enum for Command Bucket
+const (
+	CommandBucketUser 		CommandBucket = 1
+	CommandBucketChannel 	CommandBucket = 2
+	// Same as CommandBucketChannel in direct messages
+	CommandBucketGuild 		CommandBucket = 3
+	CommandBucketGlobal 	CommandBucket = 4
+)
+
+// Key of the bucket the message belongs to
+func (b CommandBucket) key(m *discordgo.Message) string {
+	switch b {
+	case 0, CommandBucketUser:
+		return "user:" + m.Author.ID
+	case CommandBucketChannel:
+		return "channel:" + m.ChannelID
+	case CommandBucketGuild:
+		if m.GuildID == "" {
+			return "channel:" + m.ChannelID
+		}
+		return "guild:" + m.GuildID
+	case CommandBucketGlobal:
+		return "global"
+	default:
+		panic("There is no such Bucket")
+	}
+}
+
+func (b CommandBucket) valid() bool {
+	return b <= CommandBucketGlobal
+}
+
+// Allow Rate uses of a command per Per duration in each bucket
+type Cooldown struct {
+	Rate 		int
+	Per 		time.Duration
+	Bucket 		CommandBucket
+
+	// Members with one of these roles are not limited
+	BypassRoles []string
+}
+
+// Return an error if the cooldown can never let the command run or has no bucket
+func (c *Cooldown) validate() error {
+	if c.Rate <= 0 || c.Per <= 0 {
+		return ErrInvalidCooldown
+	}
+	if !c.Bucket.valid() {
+		return ErrUnknownBucket
+	}
+	return nil
+}
+
+// Check if the author of the message is not limited by the cooldown
+// Owners of the bot always bypass cooldowns
+func (c *Cooldown) bypassed(r *MessageCommandRouter, m *discordgo.Message) bool {
+	if r.IsOwner(m.Author.ID) {
+		return true
+	}
+	if m.Member == nil {
+		return false
+	}
+	for _, role := range m.Member.Roles {
+		for _, bypass := range c.BypassRoles {
+			if role == bypass {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type cooldownKey struct {
+	cmd 	*MessageCommand
+	bucket 	string
+}
+
+// Uses of a bucket in the current window
+type cooldownWindow struct {
+	start 	time.Time
+	per 	time.Duration
+	uses 	int
+}
+
+// Track cooldowns of every command of a router
+type cooldownMapping struct {
+	sync.Mutex
+	windows 	map[cooldownKey]*cooldownWindow
+	lastSweep 	time.Time
+}
+
+// Count one use of the command by the message
+// Return how long to wait if the bucket has no use left
+func (cm *cooldownMapping) update(cmd *MessageCommand, m *discordgo.Message, now time.Time) (remaining time.Duration, ok bool) {
+	c := cmd.Cooldown
+
+	cm.Lock()
+	defer cm.Unlock()
+
+	if cm.windows == nil {
+		cm.windows = make(map[cooldownKey]*cooldownWindow)
+	}
+	cm.sweep(now)
+
+	key := cooldownKey{cmd, c.Bucket.key(m)}
+	w, exists := cm.windows[key]
+	if !exists || now.Sub(w.start) >= c.Per {
+		w = &cooldownWindow{start: now, per: c.Per}
+		cm.windows[key] = w
+	}
+
+	if w.uses >= c.Rate {
+		return w.start.Add(c.Per).Sub(now), false
+	}
+	w.uses++
+	return 0, true
+}
+
+// Give back a use counted by update, if its window has not expired yet
+func (cm *cooldownMapping) refund(cmd *MessageCommand, m *discordgo.Message, now time.Time) {
+	cm.Lock()
+	defer cm.Unlock()
+
+	key := cooldownKey{cmd, cmd.Cooldown.Bucket.key(m)}
+	if w, ok := cm.windows[key]; ok && now.Sub(w.start) < w.per && w.uses > 0 {
+		w.uses--
+	}
+}
+
+// Remove expired windows at most once a minute so the map does not grow forever
+func (cm *cooldownMapping) sweep(now time.Time) {
+	if now.Sub(cm.lastSweep) < time.Minute {
+		return
+	}
+	cm.lastSweep = now
+	for key, w := range cm.windows {
+		if now.Sub(w.start) >= w.per {
+			delete(cm.windows, key)
+		}
+	}
+}
+
+// Count a use of the command, return a CooldownError if it is cooling down for the message
+// The returned function gives the use back when the command could not be run after all
+func (r *MessageCommandRouter) checkCooldown(cmd *MessageCommand, m *discordgo.Message) (refund func(), err error) {
+	if cmd.Cooldown == nil || cmd.Cooldown.bypassed(r, m) {
+		return func() {}, nil
+	}
+	if remaining, ok := r.cooldowns.update(cmd, m, time.Now()); !ok {
+		return nil, &CooldownError{cmd, remaining}
+	}
+	return func() {
+		r.cooldowns.refund(cmd, m, time.Now())
+	}, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func cooldownMessage(userID string, channelID string) *discordgo.Message {
+	return &discordgo.Message{
+		Author: 	&discordgo.User{ID: userID},
+		ChannelID: 	channelID,
+	}
+}
+
+func TestCooldownWindow(t *testing.T) {
+	var cm cooldownMapping
+	cmd := &MessageCommand{Name: "roll", Cooldown: &Cooldown{Rate: 2, Per: 10 * time.Second}}
+	m := cooldownMessage("1", "10")
+	now := time.Now()
+
+	if _, ok := cm.update(cmd, m, now); !ok {
+		t.Fatal("first use is limited")
+	}
+	if _, ok := cm.update(cmd, m, now.Add(time.Second)); !ok {
+		t.Fatal("second use is limited")
+	}
+	if remaining, ok := cm.update(cmd, m, now.Add(3 * time.Second)); ok || remaining != 7 * time.Second {
+		t.Fatalf("third use = %v, %v, want 7s, false", remaining, ok)
+	}
+	// Another user has its own bucket
+	if _, ok := cm.update(cmd, cooldownMessage("2", "10"), now.Add(3 * time.Second)); !ok {
+		t.Fatal("use of another user is limited")
+	}
+	// The window is over
+	if _, ok := cm.update(cmd, m, now.Add(10 * time.Second)); !ok {
+		t.Fatal("use after the window is limited")
+	}
+}
+
+func TestCooldownBuckets(t *testing.T) {
+	tests := []struct {
+		bucket 	CommandBucket
+		other 	*discordgo.Message
+		shared 	bool
+	}{
+		{0, cooldownMessage("2", "10"), false},
+		{CommandBucketUser, cooldownMessage("1", "20"), true},
+		{CommandBucketChannel, cooldownMessage("2", "10"), true},
+		{CommandBucketChannel, cooldownMessage("1", "20"), false},
+		{CommandBucketGuild, cooldownMessage("2", "20"), false},
+		{CommandBucketGlobal, cooldownMessage("2", "20"), true},
+	}
+
+	for _, tt := range tests {
+		var cm cooldownMapping
+		cmd := &MessageCommand{Name: "roll", Cooldown: &Cooldown{Rate: 1, Per: time.Minute, Bucket: tt.bucket}}
+		now := time.Now()
+
+		cm.update(cmd, cooldownMessage("1", "10"), now)
+		if _, ok := cm.update(cmd, tt.other, now); ok == tt.shared {
+			t.Errorf("bucket %d: second use allowed = %v, want %v", tt.bucket, ok, !tt.shared)
+		}
+	}
+}
+
+func TestCooldownRefund(t *testing.T) {
+	var cm cooldownMapping
+	cmd := &MessageCommand{Name: "roll", Cooldown: &Cooldown{Rate: 1, Per: time.Minute}}
+	m := cooldownMessage("1", "10")
+	now := time.Now()
+
+	cm.update(cmd, m, now)
+	cm.refund(cmd, m, now)
+	if _, ok := cm.update(cmd, m, now); !ok {
+		t.Fatal("refunded use is still counted")
+	}
+
+	// Refunds never give more uses than Rate
+	cm.refund(cmd, m, now)
+	cm.refund(cmd, m, now)
+	cm.update(cmd, m, now)
+	if _, ok := cm.update(cmd, m, now); ok {
+		t.Fatal("refunds gave more uses than Rate")
+	}
+}
+
+func TestCooldownSweep(t *testing.T) {
+	var cm cooldownMapping
+	cmd := &MessageCommand{Name: "roll", Cooldown: &Cooldown{Rate: 1, Per: time.Second}}
+	now := time.Now()
+
+	cm.update(cmd, cooldownMessage("1", "10"), now)
+	cm.update(cmd, cooldownMessage("2", "10"), now.Add(2 * time.Minute))
+	if len(cm.windows) != 1 {
+		t.Fatalf("%d windows after sweep, want 1", len(cm.windows))
+	}
+}
+
+func TestCooldownValidate(t *testing.T) {
+	tests := []struct {
+		cooldown 	*Cooldown
+		err 		error
+	}{
+		{&Cooldown{Rate: 1, Per: time.Second}, nil},
+		{&Cooldown{Rate: 1, Per: time.Second, Bucket: CommandBucketGlobal}, nil},
+		{&Cooldown{Rate: 0, Per: time.Second}, ErrInvalidCooldown},
+		{&Cooldown{Rate: 1, Per: 0}, ErrInvalidCooldown},
+		{&Cooldown{Rate: 1, Per: time.Second, Bucket: 42}, ErrUnknownBucket},
+	}
+
+	for _, tt := range tests {
+		sub := &MessageCommand{Name: "sub", Cooldown: tt.cooldown}
+		cmd := NewMessageCommand("cmd", "", nil, false, nil, []*MessageCommand{sub}, nil)
+		err := NewMessageCommandRouter(nil).AddCommand(cmd)
+
+		var invalid *InvalidCommandError
+		if !errors.Is(err, tt.err) || (err != nil && (!errors.As(err, &invalid) || invalid.Command != sub)) {
+			t.Errorf("AddCommand with %+v = %v, want %v", tt.cooldown, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Value of MessageCommandParamTypeEmoji, a unicode or custom emoji
+type Emoji struct {
+	// Empty for unicode emojis
+	ID 			string
+
+	// The emoji itself for unicode emojis
+	Name 		string
+
+	Animated 	bool
+}
+
+// Format used by the API, like in MessageReactionAdd: the emoji itself or name:id
+func (e *Emoji) APIName() string {
+	if e.ID == "" {
+		return e.Name
+	}
+	return e.Name + ":" + e.ID
+}
+
+// Format used in messages: the emoji itself, <:name:id> or <a:name:id>
+func (e *Emoji) String() string {
+	if e.ID == "" {
+		return e.Name
+	}
+	if e.Animated {
+		return "<a:" + e.Name + ":" + e.ID + ">"
+	}
+	return "<:" + e.Name + ":" + e.ID + ">"
+}
+
+// Runes displayed as emojis by default, from the Emoji_Presentation property of Unicode 15
+// The whole Symbols and Pictographs Extended-A block is accepted for emojis added later
+var emojiPresentationRanges = [][2]rune{
+	{0x231A, 0x231B}, {0x23E9, 0x23EC}, {0x23F0, 0x23F0}, {0x23F3, 0x23F3},
+	{0x25FD, 0x25FE}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267F, 0x267F},
+	{0x2693, 0x2693}, {0x26A1, 0x26A1}, {0x26AA, 0x26AB}, {0x26BD, 0x26BE},
+	{0x26C4, 0x26C5}, {0x26CE, 0x26CE}, {0x26D4, 0x26D4}, {0x26EA, 0x26EA},
+	{0x26F2, 0x26F3}, {0x26F5, 0x26F5}, {0x26FA, 0x26FA}, {0x26FD, 0x26FD},
+	{0x2705, 0x2705}, {0x270A, 0x270B}, {0x2728, 0x2728}, {0x274C, 0x274C},
+	{0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797},
+	{0x27B0, 0x27B0}, {0x27BF, 0x27BF}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50},
+	{0x2B55, 0x2B55},
+	{0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF}, {0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A},
+	{0x1F201, 0x1F201}, {0x1F21A, 0x1F21A}, {0x1F22F, 0x1F22F}, {0x1F232, 0x1F236},
+	{0x1F238, 0x1F23A}, {0x1F250, 0x1F251},
+	{0x1F300, 0x1F320}, {0x1F32D, 0x1F335}, {0x1F337, 0x1F37C}, {0x1F37E, 0x1F393},
+	{0x1F3A0, 0x1F3CA}, {0x1F3CF, 0x1F3D3}, {0x1F3E0, 0x1F3F0}, {0x1F3F4, 0x1F3F4},
+	{0x1F3F8, 0x1F43E}, {0x1F440, 0x1F440}, {0x1F442, 0x1F4FC}, {0x1F4FF, 0x1F53D},
+	{0x1F54B, 0x1F54E}, {0x1F550, 0x1F567}, {0x1F57A, 0x1F57A}, {0x1F595, 0x1F596},
+	{0x1F5A4, 0x1F5A4}, {0x1F5FB, 0x1F64F}, {0x1F680, 0x1F6C5}, {0x1F6CC, 0x1F6CC},
+	{0x1F6D0, 0x1F6D2}, {0x1F6D5, 0x1F6D7}, {0x1F6DC, 0x1F6DF}, {0x1F6EB, 0x1F6EC},
+	{0x1F6F4, 0x1F6FC}, {0x1F7E0, 0x1F7EB}, {0x1F7F0, 0x1F7F0}, {0x1F90C, 0x1F93A},
+	{0x1F93C, 0x1F945}, {0x1F947, 0x1F9FF}, {0x1FA70, 0x1FAFF},
+}
+
+// Runes displayed as text unless followed by U+FE0F, from the Emoji property of Unicode 15
+var emojiTextRanges = [][2]rune{
+	{0x00A9, 0x00A9}, {0x00AE, 0x00AE}, {0x203C, 0x203C}, {0x2049, 0x2049},
+	{0x2122, 0x2122}, {0x2139, 0x2139}, {0x2194, 0x2199}, {0x21A9, 0x21AA},
+	{0x2328, 0x2328}, {0x23CF, 0x23CF}, {0x23ED, 0x23EF}, {0x23F1, 0x23F2},
+	{0x23F8, 0x23FA}, {0x24C2, 0x24C2}, {0x25AA, 0x25AB}, {0x25B6, 0x25B6},
+	{0x25C0, 0x25C0}, {0x25FB, 0x25FC}, {0x2600, 0x2604}, {0x260E, 0x260E},
+	{0x2611, 0x2611}, {0x2618, 0x2618}, {0x261D, 0x261D}, {0x2620, 0x2620},
+	{0x2622, 0x2623}, {0x2626, 0x2626}, {0x262A, 0x262A}, {0x262E, 0x262F},
+	{0x2638, 0x263A}, {0x2640, 0x2640}, {0x2642, 0x2642}, {0x265F, 0x2660},
+	{0x2663, 0x2663}, {0x2665, 0x2666}, {0x2668, 0x2668}, {0x267B, 0x267B},
+	{0x267E, 0x267E}, {0x2692, 0x2692}, {0x2694, 0x2697}, {0x2699, 0x2699},
+	{0x269B, 0x269C}, {0x26A0, 0x26A0}, {0x26A7, 0x26A7}, {0x26B0, 0x26B1},
+	{0x26C8, 0x26C8}, {0x26CF, 0x26CF}, {0x26D1, 0x26D1}, {0x26D3, 0x26D3},
+	{0x26E9, 0x26E9}, {0x26F0, 0x26F1}, {0x26F4, 0x26F4}, {0x26F7, 0x26F9},
+	{0x2702, 0x2702}, {0x2708, 0x2709}, {0x270C, 0x270D}, {0x270F, 0x270F},
+	{0x2712, 0x2712}, {0x2714, 0x2714}, {0x2716, 0x2716}, {0x271D, 0x271D},
+	{0x2721, 0x2721}, {0x2733, 0x2734}, {0x2744, 0x2744}, {0x2747, 0x2747},
+	{0x2763, 0x2764}, {0x27A1, 0x27A1}, {0x2934, 0x2935}, {0x2B05, 0x2B07},
+	{0x3030, 0x3030}, {0x303D, 0x303D}, {0x3297, 0x3297}, {0x3299, 0x3299},
+	{0x1F170, 0x1F171}, {0x1F17E, 0x1F17F}, {0x1F202, 0x1F202}, {0x1F237, 0x1F237},
+	{0x1F321, 0x1F321}, {0x1F324, 0x1F32C}, {0x1F336, 0x1F336}, {0x1F37D, 0x1F37D},
+	{0x1F396, 0x1F397}, {0x1F399, 0x1F39B}, {0x1F39E, 0x1F39F}, {0x1F3CB, 0x1F3CE},
+	{0x1F3D4, 0x1F3DF}, {0x1F3F3, 0x1F3F3}, {0x1F3F5, 0x1F3F5}, {0x1F3F7, 0x1F3F7},
+	{0x1F43F, 0x1F43F}, {0x1F441, 0x1F441}, {0x1F4FD, 0x1F4FD}, {0x1F549, 0x1F54A},
+	{0x1F56F, 0x1F570}, {0x1F573, 0x1F579}, {0x1F587, 0x1F587}, {0x1F58A, 0x1F58D},
+	{0x1F590, 0x1F590}, {0x1F5A5, 0x1F5A5}, {0x1F5A8, 0x1F5A8}, {0x1F5B1, 0x1F5B2},
+	{0x1F5BC, 0x1F5BC}, {0x1F5C2, 0x1F5C4}, {0x1F5D1, 0x1F5D3}, {0x1F5DC, 0x1F5DE},
+	{0x1F5E1, 0x1F5E1}, {0x1F5E3, 0x1F5E3}, {0x1F5E8, 0x1F5E8}, {0x1F5EF, 0x1F5EF},
+	{0x1F5F3, 0x1F5F3}, {0x1F5FA, 0x1F5FA}, {0x1F6CB, 0x1F6CB}, {0x1F6CD, 0x1F6CF},
+	{0x1F6E0, 0x1F6E5}, {0x1F6E9, 0x1F6E9}, {0x1F6F0, 0x1F6F0}, {0x1F6F3, 0x1F6F3},
+}
+
+// Runes combining with emojis
+const (
+	emojiJoiner 			= 0x200D
+	emojiVariationSelector 	= 0xFE0F
+	emojiKeycap 			= 0x20E3
+	emojiBlackFlag 			= 0x1F3F4
+	emojiCancelTag 			= 0xE007F
+)
+
+func inRuneRanges(r rune, ranges [][2]rune) bool {
+	for _, rg := range ranges {
+		if r >= rg[0] && r <= rg[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmojiModifier(r rune) bool {
+	return r >= 0x1F3FB && r <= 0x1F3FF
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isEmojiTag(r rune) bool {
+	return r >= 0xE0020 && r <= 0xE007E
+}
+
+// Check if the string is exactly one unicode emoji
+//    Keycaps like 1️⃣: [0-9#*], an optional U+FE0F and U+20E3
+//    Flags like 🇫🇷: two regional indicators
+//    Subdivision flags like 🏴󠁧󠁢󠁳󠁣󠁴󠁿: the black flag, tags and the cancel tag
+//    Other emojis: elements joined by U+200D, each one an emoji with an optional U+FE0F or skin tone
+// A lone emoji displayed as text by default, like ©, needs U+FE0F or a skin tone
+func isUnicodeEmoji(str string) bool {
+	runes := []rune(str)
+	if len(runes) == 0 {
+		return false
+	}
+
+	switch first := runes[0]; {
+	case strings.ContainsRune("0123456789#*", first):
+		rest := runes[1:]
+		if len(rest) != 0 && rest[0] == emojiVariationSelector {
+			rest = rest[1:]
+		}
+		return len(rest) == 1 && rest[0] == emojiKeycap
+	case isRegionalIndicator(first):
+		return len(runes) == 2 && isRegionalIndicator(runes[1])
+	case first == emojiBlackFlag && len(runes) > 1 && isEmojiTag(runes[1]):
+		for _, r := range runes[1:len(runes)-1] {
+			if !isEmojiTag(r) {
+				return false
+			}
+		}
+		return runes[len(runes)-1] == emojiCancelTag
+	}
+
+	elements := 0
+	textOnly := false
+	for i := 0; i < len(runes); i++ {
+		if elements != 0 {
+			if runes[i] != emojiJoiner || i + 1 == len(runes) {
+				return false
+			}
+			i++
+		}
+
+		r := runes[i]
+		presentation := inRuneRanges(r, emojiPresentationRanges)
+		if !presentation && !inRuneRanges(r, emojiTextRanges) {
+			return false
+		}
+		if i + 1 < len(runes) && (runes[i+1] == emojiVariationSelector || isEmojiModifier(runes[i+1])) {
+			i++
+		} else if !presentation {
+			textOnly = true
+		}
+		elements++
+	}
+
+	// Text symbols only count as emojis in sequences
+	return !(elements == 1 && textOnly)
+}
+
+// Find a unicode emoji, a custom emoji <:name:id> or <a:name:id>, or a custom emoji of the guild by name
+// Names can be written with or without colons, exact matches win over case insensitive ones
+// Return an AmbiguousArgumentError if several emojis match case insensitively
+func emojiConverter(s *discordgo.Session, guildID string, str string) (*Emoji, error) {
+	if m := customEmojiRegex.FindStringSubmatch(str); m != nil {
+		return &Emoji{
+			ID: 		m[3],
+			Name: 		m[2],
+			Animated: 	m[1] == "a",
+		}, nil
+	}
+	if isUnicodeEmoji(str) {
+		return &Emoji{Name: str}, nil
+	}
+
+	name := strings.Trim(str, ":")
+	if guildID == "" || name == "" {
+		return nil, fmt.Errorf("%s is not an emoji", str)
+	}
+	g, err := s.State.Guild(guildID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find an emoji named %s", name)
+	}
+	s.State.RLock()
+	emojis := append([]*discordgo.Emoji{}, g.Emojis...)
+	s.State.RUnlock()
+
+	var found *discordgo.Emoji
+	folded := []*discordgo.Emoji{}
+	for _, e := range emojis {
+		if e.Name == name {
+			found = e
+			break
+		}
+		if strings.EqualFold(e.Name, name) {
+			folded = append(folded, e)
+		}
+	}
+	if found == nil {
+		switch len(folded) {
+		case 0:
+			return nil, fmt.Errorf("cannot find an emoji named %s", name)
+		case 1:
+			found = folded[0]
+		default:
+			candidates := make([]string, len(folded))
+			for i, e := range folded {
+				candidates[i] = e.Name
+			}
+			return nil, &AmbiguousArgumentError{str, candidates}
+		}
+	}
+	return &Emoji{
+		ID: 		found.ID,
+		Name: 		found.Name,
+		Animated: 	found.Animated,
+	}, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestIsUnicodeEmoji(t *testing.T) {
+	tests := []struct {
+		in 		string
+		want 	bool
+	}{
+		{"👍", true},
+		{"👍🏽", true},
+		{"❤\ufe0f", true},
+		{"©\ufe0f", true},
+		{"☝🏻", true},
+		{"⌚", true},
+		{"🫠", true},
+		{"1\ufe0f\u20e3", true},
+		{"#\u20e3", true},
+		{"🇫🇷", true},
+		{"👨\u200d👩\u200d👧", true},
+		{"🧑🏽\u200d💻", true},
+		{"🏳\ufe0f\u200d🌈", true},
+		{"❤\ufe0f\u200d🔥", true},
+		{"🏴󠁧󠁢󠁳󠁣󠁴󠁿", true},
+
+		{"", false},
+		{"a", false},
+		{"1", false},
+		{"#", false},
+		{"1\u20e3\u20e3", false},
+		{"\u200d", false},
+		{"\ufe0f", false},
+		{"\u200d\ufe0f", false},
+		{"👍\u200d", false},
+		{"\u200d👍", false},
+		{"👍\u200d\u200d👍", false},
+		{"→", false},
+		{"■", false},
+		{"©", false},
+		{"❤", false},
+		{"😀😀", false},
+		{"👍 ", false},
+		{"🇫", false},
+		{"🇫🇷🇫", false},
+		{"🏴\U000E0067\U000E0062", false},
+		{"\U000E007F", false},
+	}
+
+	for _, tt := range tests {
+		if got := isUnicodeEmoji(tt.in); got != tt.want {
+			t.Errorf("isUnicodeEmoji(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEmojiConverter(t *testing.T) {
+	s, _ := discordgo.New("")
+	s.State = discordgo.NewState()
+	s.State.GuildAdd(&discordgo.Guild{ID: "1", Emojis: []*discordgo.Emoji{
+		{ID: "5", Name: "pog"},
+		{ID: "6", Name: "POG", Animated: true},
+		{ID: "7", Name: "kek"},
+	}})
+
+	tests := []struct {
+		in 		string
+		want 	string
+	}{
+		{"👍", "👍"},
+		{"1\ufe0f\u20e3", "1\ufe0f\u20e3"},
+		{"<a:x:9>", "x:9"},
+		{"<:x:9>", "x:9"},
+		{":kek:", "kek:7"},
+		{"KEK", "kek:7"},
+		{"pog", "pog:5"},
+	}
+	for _, tt := range tests {
+		if e, err := emojiConverter(s, "1", tt.in); err != nil || e.APIName() != tt.want {
+			t.Errorf("emojiConverter(%q) = %v, %v, want %s", tt.in, e, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"a", "©", "→", "::", ":nope:", "😀😀"} {
+		if e, err := emojiConverter(s, "1", in); err == nil {
+			t.Errorf("emojiConverter(%q) = %v, want an error", in, e)
+		}
+	}
+
+	var ambiguous *AmbiguousArgumentError
+	if _, err := emojiConverter(s, "1", "Pog"); !errors.As(err, &ambiguous) {
+		t.Errorf("emojiConverter(\"Pog\") error = %v, want an AmbiguousArgumentError", err)
+	}
+
+	if e, err := emojiConverter(s, "", "<a:x:9>"); err != nil || e.String() != "<a:x:9>" {
+		t.Errorf("emojiConverter(\"<a:x:9>\") = %v, %v", e, err)
+	}
+}
@@ -1,6 +1,13 @@
 package main
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
 
 // Base error
 var (
@@ -15,4 +22,311 @@ var (
 	ErrNilPage         	= errors.New("err: MessageSend is nil")
 	ErrNotRunning       = errors.New("err: Not running")
 	ErrPagesEmpty		= errors.New("err: No page")
-)
\ No newline at end of file
+)
+
+// Error for parsing message content
+var (
+	ErrUnclosedQuote 	= errors.New("err: Quote is not closed")
+	ErrUnclosedCodeBlock = errors.New("err: Code block is not closed")
+)
+
+// Error for custom prefixes
+var (
+	ErrEmptyPrefix 		= errors.New("err: Prefix is empty")
+	ErrTooManyPrefixes 	= errors.New("err: Too many prefixes")
+	ErrPrefixTooLong 	= errors.New("err: Prefix is too long")
+)
+
+// Error for flags
+var (
+	ErrUnknownFlag 		= errors.New("err: Unknown flag")
+	ErrMissingFlagValue = errors.New("err: Flag needs a value")
+)
+
+// Error for adding commands, wrapped in an InvalidCommandError
+var (
+	ErrInvalidCooldown 	= errors.New("err: Cooldown needs a positive Rate and Per")
+	ErrInvalidMaxConcurrency = errors.New("err: MaxConcurrency needs a positive Limit and cannot have a negative MaxWaiting or WaitTimeout")
+	ErrUnknownBucket 	= errors.New("err: There is no such Bucket")
+)
+
+// Error for executor
+var (
+	ErrExecutorFull 	= errors.New("err: Too many tasks are waiting")
+	ErrTaskDropped 		= errors.New("err: Task dropped")
+)
+
+// Error for converting arguments
+var (
+	ErrUnknownParamType = errors.New("err: No converter for the param type")
+	ErrNotInGuild 		= errors.New("err: Can only be found in a server")
+)
+
+// Max number of candidates shown by AmbiguousArgumentError
+const maxShownCandidates = 10
+
+// Several values match the argument
+type AmbiguousArgumentError struct {
+	Arg 		string
+	Candidates 	[]string
+}
+
+func (e *AmbiguousArgumentError) Error() string {
+	shown := e.Candidates
+	more := ""
+	if len(shown) > maxShownCandidates {
+		more = fmt.Sprintf(" and %d more", len(shown) - maxShownCandidates)
+		shown = shown[:maxShownCandidates]
+	}
+	return fmt.Sprintf("%s matches several values: %s%s", e.Arg, strings.Join(shown, ", "), more)
+}
+
+// Error for built-in checks
+var (
+	ErrGuildOnly 		= errors.New("err: Command can only be used in a server")
+	ErrDMOnly 			= errors.New("err: Command can only be used in direct messages")
+	ErrOwnerOnly 		= errors.New("err: Command can only be used by the owners")
+	ErrNSFWOnly 		= errors.New("err: Command can only be used in a NSFW channel")
+)
+
+// Number of positional arguments does not match the params of the command
+type ArgumentCountError struct {
+	Command 	*MessageCommand
+	Got 		int
+	Min 		int
+	// -1 if the command takes any number of arguments
+	Max 		int
+}
+
+func (e *ArgumentCountError) Error() string {
+	if e.Max == -1 {
+		return fmt.Sprintf("%s takes at least %d argument(s) but got %d", e.Command.FullName(), e.Min, e.Got)
+	}
+	if e.Min == e.Max {
+		return fmt.Sprintf("%s takes %d argument(s) but got %d", e.Command.FullName(), e.Min, e.Got)
+	}
+	return fmt.Sprintf("%s takes %d to %d argument(s) but got %d", e.Command.FullName(), e.Min, e.Max, e.Got)
+}
+
+// An argument or a flag cannot be converted to the type of its param
+type ConversionError struct {
+	// Name of the param, or --name for flags
+	Param 	string
+	Raw 	string
+	Err 	error
+
+	// Formats accepted by the converter of the param, see ArgumentConverter
+	Help 	string
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("invalid value %q for %s: %v", e.Raw, e.Param, e.Err)
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// A flag is unknown or misses its value
+type FlagError struct {
+	Flag 	string
+	Err 	error
+}
+
+func (e *FlagError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Flag, e.Err)
+}
+
+func (e *FlagError) Unwrap() error {
+	return e.Err
+}
+
+// No command is registered with the name
+type CommandNotFoundError struct {
+	Name 			string
+
+	// Closest command names, only filled if SuggestCommands of the router is enabled
+	Suggestions 	[]string
+}
+
+func (e *CommandNotFoundError) Error() string {
+	return fmt.Sprintf("command %s not found", e.Name)
+}
+
+// A check prevents the command from running
+type CheckFailedError struct {
+	Command 	*MessageCommand
+	// Reason shown to the user
+	Err 		error
+}
+
+func (e *CheckFailedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CheckFailedError) Unwrap() error {
+	return e.Err
+}
+
+// The user or the bot lacks permissions in the channel
+type MissingPermissionsError struct {
+	// Whether the bot lacks the permissions instead of the user
+	Bot 		bool
+	Missing 	int64
+}
+
+func (e *MissingPermissionsError) Error() string {
+	if e.Bot {
+		return "bot is missing permission(s): " + permissionString(e.Missing)
+	}
+	return "missing permission(s): " + permissionString(e.Missing)
+}
+
+// A name or alias of the command is already used by another command
+type CommandExistsError struct {
+	Key 		string
+	Existing 	*MessageCommand
+	Command 	*MessageCommand
+}
+
+func (e *CommandExistsError) Error() string {
+	return fmt.Sprintf("cannot add %s: %s is already used by %s", e.Command.Name, e.Key, e.Existing.Name)
+}
+
+// The command or one of its subcommands cannot be added because it is misconfigured
+type InvalidCommandError struct {
+	Command 	*MessageCommand
+	Err 		error
+}
+
+func (e *InvalidCommandError) Error() string {
+	return fmt.Sprintf("cannot add %s: %v", e.Command.FullName(), e.Err)
+}
+
+func (e *InvalidCommandError) Unwrap() error {
+	return e.Err
+}
+
+// The command or its category is disabled in the server or the channel
+type CommandDisabledError struct {
+	Command 	*MessageCommand
+	ChannelID 	string
+}
+
+func (e *CommandDisabledError) Error() string {
+	return fmt.Sprintf("%s is disabled in this channel", e.Command.FullName())
+}
+
+// The command was used too often in its cooldown bucket
+type CooldownError struct {
+	Command 	*MessageCommand
+	// Time left before the command can be used again
+	Remaining 	time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("%s is on cooldown, try again in %v", e.Command.FullName(), e.Remaining.Round(100 * time.Millisecond))
+}
+
+// Every use of the command allowed at once in the bucket is already running
+type MaxConcurrencyError struct {
+	Command 	*MessageCommand
+	Limit 		int
+	Bucket 		CommandBucket
+}
+
+func (e *MaxConcurrencyError) Error() string {
+	var scope string
+	switch e.Bucket {
+	case 0, CommandBucketUser:
+		scope = " per user"
+	case CommandBucketChannel:
+		scope = " per channel"
+	case CommandBucketGuild:
+		scope = " per server"
+	}
+	return fmt.Sprintf("%s can only run %d time(s) at once%s, try again later", e.Command.FullName(), e.Limit, scope)
+}
+
+// Reply to the invoking message with a description of the error
+// Unknown commands are ignored unless there are suggestions, unexpected errors are logged and reported without details
+func DefaultErrorHandler(ctx *MessageCommandContext, err error) {
+	var (
+		argCount 	*ArgumentCountError
+		conversion 	*ConversionError
+		flag 		*FlagError
+		notFound 	*CommandNotFoundError
+		check 		*CheckFailedError
+		cooldown 	*CooldownError
+		disabled 	*CommandDisabledError
+		concurrency *MaxConcurrencyError
+	)
+
+	switch {
+	case errors.As(err, &notFound):
+		if len(notFound.Suggestions) != 0 {
+			ctx.RespondText("Unknown command `", notFound.Name, "`. Did you mean `", strings.Join(notFound.Suggestions, "`, `"), "`?")
+		}
+	case errors.As(err, &argCount):
+		ctx.RespondText(argCount.Error(), "\nUsage: ", argCount.Command.usage(ctx.converters()))
+	case errors.As(err, &conversion) && conversion.Help != "":
+		ctx.RespondText(conversion.Error(), "\nExpected ", conversion.Help)
+	case errors.As(err, &conversion):
+		ctx.RespondText(conversion.Error(), "\nUse help <command> for more info")
+	case errors.As(err, &flag):
+		ctx.RespondText(flag.Error(), "\nUse help <command> for more info")
+	case errors.As(err, &check):
+		ctx.RespondText(checkFailedMessage(check))
+	case errors.As(err, &cooldown):
+		ctx.RespondText(cooldown.Error())
+	case errors.As(err, &disabled):
+		ctx.RespondText(disabled.Error())
+	case errors.As(err, &concurrency):
+		ctx.RespondText(concurrency.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		ctx.RespondText("The command took too long and was stopped")
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrTaskDropped):
+		// The bot is shutting down, or too busy to tell the user
+	case errors.Is(err, ErrExecutorFull):
+		ctx.RespondText("I am busy right now, try again in a moment")
+	case errors.Is(err, ErrEmptyPrefix):
+		ctx.RespondText("A prefix cannot be empty or only whitespaces")
+	case errors.Is(err, ErrTooManyPrefixes):
+		ctx.RespondText(fmt.Sprintf("A server can have at most %d prefixes", maxPrefixes))
+	case errors.Is(err, ErrPrefixTooLong):
+		ctx.RespondText(fmt.Sprintf("A prefix can have at most %d characters", maxPrefixLength))
+	case errors.Is(err, ErrUnclosedQuote):
+		ctx.RespondText("Cannot read your command: a quote is not closed")
+	case errors.Is(err, ErrUnclosedCodeBlock):
+		ctx.RespondText("Cannot read your command: a code block is not closed")
+	default:
+		name := ctx.Trigger
+		if ctx.Command != nil {
+			name = ctx.Command.FullName()
+		}
+		log.Printf("command %s: %v", name, err)
+		ctx.RespondText("Something went wrong while running the command")
+	}
+}
+
+// Describe why a check failed
+func checkFailedMessage(e *CheckFailedError) string {
+	var perm *MissingPermissionsError
+
+	switch {
+	case errors.Is(e, ErrGuildOnly):
+		return "This command can only be used in a server"
+	case errors.Is(e, ErrDMOnly):
+		return "This command can only be used in direct messages"
+	case errors.Is(e, ErrOwnerOnly):
+		return "This command can only be used by the owners of the bot"
+	case errors.Is(e, ErrNSFWOnly):
+		return "This command can only be used in a NSFW channel"
+	case errors.As(e, &perm) && perm.Bot:
+		return "I need the following permission(s) to do that: " + permissionString(perm.Missing)
+	case errors.As(e, &perm):
+		return "You need the following permission(s) to do that: " + permissionString(perm.Missing)
+	default:
+		return e.Error()
+	}
+}
\ No newline at end of file
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// How long event handlers wait for room in the queue of an executor with ExecutorOverflowBlock
+// They must not block for long, events of the session are delivered by them
+const submitTimeout = 5 * time.Second
+
+// What an Executor does with a task when its queue is full
+type ExecutorOverflow uint8
+
+// Enum for Executor Overflow
+const (
+	// Wait until there is room in the queue
+	ExecutorOverflowBlock 	ExecutorOverflow = 1
+	// Discard the task, Submit returns ErrTaskDropped
+	ExecutorOverflowDrop 	ExecutorOverflow = 2
+	// Discard the task, Submit returns ErrExecutorFull so the user can be told
+	ExecutorOverflowReject 	ExecutorOverflow = 3
+)
+
+// Snapshot of the activity of an Executor
+type ExecutorStats struct {
+	Workers 	int
+	QueueSize 	int
+
+	// Tasks being run
+	Running 	int
+	// Tasks waiting for a worker
+	Queued 		int
+
+	// Tasks discarded since the executor was created
+	Dropped 	uint64
+	Rejected 	uint64
+}
+
+// Run tasks on a fixed number of workers with a bounded queue
+type Executor struct {
+	// Updated atomically, first for 64-bit alignment
+	running 	int64
+	dropped 	uint64
+	rejected 	uint64
+
+	workers 	int
+	overflow 	ExecutorOverflow
+	tasks 		chan func()
+}
+
+// Start an executor running at most workers tasks at once, with queueSize tasks waiting
+// overflow decides what happens to tasks submitted while the queue is full
+func NewExecutor(workers int, queueSize int, overflow ExecutorOverflow) *Executor {
+	if workers <= 0 {
+		panic("Executor needs at least one worker")
+	}
+	if queueSize < 0 {
+		panic("Queue size of Executor cannot be negative")
+	}
+	switch overflow {
+	case ExecutorOverflowBlock, ExecutorOverflowDrop, ExecutorOverflowReject:
+	default:
+		panic("There is no such Overflow")
+	}
+
+	e := &Executor{
+		workers: 	workers,
+		overflow: 	overflow,
+		tasks: 		make(chan func(), queueSize),
+	}
+	for i := 0; i < workers; i++ {
+		go e.work()
+	}
+	return e
+}
+
+func (e *Executor) work() {
+	for task := range e.tasks {
+		atomic.AddInt64(&e.running, 1)
+		task()
+		atomic.AddInt64(&e.running, -1)
+	}
+}
+
+// Queue the task, or handle it according to the overflow policy if the queue is full
+// When blocking, give up and return the error of ctx once it is done
+func (e *Executor) Submit(ctx context.Context, task func()) error {
+	select {
+	case e.tasks <- task:
+		return nil
+	default:
+	}
+
+	switch e.overflow {
+	case ExecutorOverflowDrop:
+		atomic.AddUint64(&e.dropped, 1)
+		return ErrTaskDropped
+	case ExecutorOverflowReject:
+		atomic.AddUint64(&e.rejected, 1)
+		return ErrExecutorFull
+	}
+
+	select {
+	case e.tasks <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Same as Submit, return ErrExecutorFull if the task is still blocked after timeout
+func (e *Executor) submitWithin(ctx context.Context, timeout time.Duration, task func()) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := e.Submit(waitCtx, task)
+	if err != nil && ctx.Err() == nil && waitCtx.Err() != nil {
+		atomic.AddUint64(&e.rejected, 1)
+		return ErrExecutorFull
+	}
+	return err
+}
+
+func (e *Executor) Stats() ExecutorStats {
+	return ExecutorStats{
+		Workers: 	e.workers,
+		QueueSize: 	cap(e.tasks),
+		Running: 	int(atomic.LoadInt64(&e.running)),
+		Queued: 	len(e.tasks),
+		Dropped: 	atomic.LoadUint64(&e.dropped),
+		Rejected: 	atomic.LoadUint64(&e.rejected),
+	}
+}
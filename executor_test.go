@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Keep the only worker busy and fill the queue of one task
+func fillExecutor(t *testing.T, e *Executor, block chan struct{}) {
+	e.Submit(context.Background(), func() { <-block })
+	for deadline := time.Now().Add(time.Second); e.Stats().Running != 1; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the worker did not start the task")
+		}
+	}
+	if err := e.Submit(context.Background(), func() { <-block }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecutorOverflow(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	e := NewExecutor(1, 1, ExecutorOverflowReject)
+	fillExecutor(t, e, block)
+	if err := e.Submit(context.Background(), func() {}); !errors.Is(err, ErrExecutorFull) {
+		t.Errorf("Submit to a full rejecting executor = %v, want %v", err, ErrExecutorFull)
+	}
+
+	d := NewExecutor(1, 1, ExecutorOverflowDrop)
+	fillExecutor(t, d, block)
+	if err := d.Submit(context.Background(), func() {}); !errors.Is(err, ErrTaskDropped) {
+		t.Errorf("Submit to a full dropping executor = %v, want %v", err, ErrTaskDropped)
+	}
+	if stats := d.Stats(); stats.Dropped != 1 {
+		t.Errorf("Dropped = %d, want 1", stats.Dropped)
+	}
+}
+
+func TestExecutorSubmitWithin(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	e := NewExecutor(1, 1, ExecutorOverflowBlock)
+	fillExecutor(t, e, block)
+
+	// Still blocked after the timeout
+	if err := e.submitWithin(context.Background(), 10 * time.Millisecond, func() {}); !errors.Is(err, ErrExecutorFull) {
+		t.Errorf("submitWithin to a full blocking executor = %v, want %v", err, ErrExecutorFull)
+	}
+	if stats := e.Stats(); stats.Rejected != 1 {
+		t.Errorf("Rejected = %d, want 1", stats.Rejected)
+	}
+
+	// Shutting down is not a full executor
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := e.submitWithin(ctx, time.Second, func() {}); !errors.Is(err, context.Canceled) {
+		t.Errorf("submitWithin with a cancelled context = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Named option of a message command
+//    --name value, --name=value, -s value or -s=value
+//    Boolean flags take no value (--silent) unless given with = (--silent=false)
+//    Arguments after -- are never treated as flags
+type MessageCommandFlag struct {
+	// Used as --Name and as the key in ConvertedArgs
+	Name 		string
+
+	// Optional one letter alias used as -Short
+	Short 		string
+
+	Type 		MessageCommandParamType
+	Description string
+
+	// Value put in ConvertedArgs when the flag is not given
+	// Boolean flags default to false, other flags are absent when Default is nil
+	Default 	interface{}
+}
+
+// Usage of the flag as shown in command usage
+func (f *MessageCommandFlag) Usage() string {
+	return f.usage(DefaultConverters)
+}
+
+// Same as Usage with type names from the converters
+func (f *MessageCommandFlag) usage(converters *ConverterRegistry) string {
+	name := "--" + f.Name
+	if f.Short != "" {
+		name = "-" + f.Short + "|" + name
+	}
+	if f.Type == MessageCommandParamTypeBoolean {
+		return "[`" + name + "`]"
+	}
+	return "[`" + name + " <" + converters.typeName(f.Type) + ">`]"
+}
+
+// Help line of the flag as shown in command embed
+func (f *MessageCommandFlag) Help() string {
+	return f.help(DefaultConverters)
+}
+
+// Same as Help with type names from the converters
+func (f *MessageCommandFlag) help(converters *ConverterRegistry) string {
+	s := "`--" + f.Name + "`"
+	if f.Short != "" {
+		s = "`-" + f.Short + "`, " + s
+	}
+	s += fmt.Sprintf(" `%s`: %s", converters.typeName(f.Type), f.Description)
+	if f.Default != nil {
+		s += fmt.Sprintf(" (default %v)", f.Default)
+	}
+	return s
+}
+
+// Declare flags of the command
+// Panic if a flag name is already used by another flag or a param
+func (cmd *MessageCommand) AddFlags(flags ...*MessageCommandFlag) {
+	for _, f := range flags {
+		if f.Name == "" || len([]rune(f.Short)) > 1 {
+			panic("Flag must have a name and at most one letter short name")
+		}
+		if cmd.GetFlag(f.Name) != nil || (f.Short != "" && cmd.getShortFlag(f.Short) != nil) {
+			panic("Flag " + f.Name + " is declared twice")
+		}
+		for _, p := range cmd.Params {
+			if p.Name == f.Name {
+				panic("Flag " + f.Name + " has the same name as a param")
+			}
+		}
+		cmd.Flags = append(cmd.Flags, f)
+	}
+	cmd.generateUsage()
+}
+
+func (cmd *MessageCommand) GetFlag(name string) *MessageCommandFlag {
+	for _, f := range cmd.Flags {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (cmd *MessageCommand) getShortFlag(short string) *MessageCommandFlag {
+	for _, f := range cmd.Flags {
+		if f.Short == short {
+			return f
+		}
+	}
+	return nil
+}
+
+// Separate flags from positional arguments
+// Unknown --name is an error, unknown -s is kept as positional argument (e.g. negative numbers)
+// Commands without flags keep every argument as positional, including -- and --name
+// flags maps name of flag to its raw value, the last one wins if a flag is repeated
+func (cmd *MessageCommand) splitFlags(arguments []string) (positional []string, flags map[string]string, err error) {
+	flags = make(map[string]string)
+	if len(cmd.Flags) == 0 {
+		return arguments, flags, nil
+	}
+	positional = make([]string, 0, len(arguments))
+
+	for i := 0; i < len(arguments); i++ {
+		arg := arguments[i]
+
+		if arg == "--" {
+			positional = append(positional, arguments[i+1:]...)
+			break
+		}
+
+		var f *MessageCommandFlag
+		name, value, hasValue := arg, "", false
+		if idx := strings.Index(arg, "="); idx != -1 {
+			name, value, hasValue = arg[:idx], arg[idx+1:], true
+		}
+
+		if strings.HasPrefix(name, "--") && len(name) > 2 {
+			if f = cmd.GetFlag(name[2:]); f == nil {
+				return nil, nil, &FlagError{name, ErrUnknownFlag}
+			}
+		} else if strings.HasPrefix(name, "-") && len(name) > 1 {
+			f = cmd.getShortFlag(name[1:])
+		}
+
+		if f == nil {
+			positional = append(positional, arg)
+			continue
+		}
+
+		if !hasValue {
+			if f.Type == MessageCommandParamTypeBoolean {
+				value = "true"
+			} else if i+1 < len(arguments) {
+				i++
+				value = arguments[i]
+			} else {
+				return nil, nil, &FlagError{name, ErrMissingFlagValue}
+			}
+		}
+		flags[f.Name] = value
+	}
+
+	return positional, flags, nil
+}
+
+// Convert the flags with the converters of the router of ctx
+// Return a ConversionError if a value cannot be converted
+func (cmd *MessageCommand) ConvertFlags(
+	ctx 	*MessageCommandContext,
+	flags 	map[string]string,
+) (convertedFlags map[string]interface{}, err error) {
+	flagMap := make(map[string]interface{})
+	converters := ctx.converters()
+
+	for _, f := range cmd.Flags {
+		raw, ok := flags[f.Name]
+		switch {
+		case ok:
+			if res, err := converters.convert(ctx, "--" + f.Name, raw, f.Type); err != nil {
+				return nil, err
+			} else {
+				flagMap[f.Name] = res
+			}
+		case f.Default != nil:
+			flagMap[f.Name] = f.Default
+		case f.Type == MessageCommandParamTypeBoolean:
+			flagMap[f.Name] = false
+		}
+	}
+
+	return flagMap, nil
+}
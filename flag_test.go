@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSplitFlags(t *testing.T) {
+	withFlags := &MessageCommand{Name: "search"}
+	withFlags.AddFlags(
+		&MessageCommandFlag{Name: "limit", Short: "l", Type: MessageCommandParamTypeInteger},
+		&MessageCommandFlag{Name: "silent", Type: MessageCommandParamTypeBoolean},
+	)
+	noFlags := &MessageCommand{Name: "say"}
+
+	tests := []struct {
+		name 		string
+		cmd 		*MessageCommand
+		args 		[]string
+		positional 	[]string
+		flags 		map[string]string
+		err 		error
+	}{
+		{"no flags declared", noFlags, []string{"hello", "---"}, []string{"hello", "---"}, map[string]string{}, nil},
+		{"no flags declared keeps --name", noFlags, []string{"--x", "--", "y"}, []string{"--x", "--", "y"}, map[string]string{}, nil},
+		{"long flag", withFlags, []string{"a", "--limit", "5"}, []string{"a"}, map[string]string{"limit": "5"}, nil},
+		{"long flag with =", withFlags, []string{"--limit=5", "a"}, []string{"a"}, map[string]string{"limit": "5"}, nil},
+		{"short flag", withFlags, []string{"-l", "5"}, []string{}, map[string]string{"limit": "5"}, nil},
+		{"boolean flag", withFlags, []string{"--silent", "a"}, []string{"a"}, map[string]string{"silent": "true"}, nil},
+		{"unknown short flag", withFlags, []string{"-5"}, []string{"-5"}, map[string]string{}, nil},
+		{"after --", withFlags, []string{"--", "--limit", "5"}, []string{"--limit", "5"}, map[string]string{}, nil},
+		{"unknown long flag", withFlags, []string{"--x"}, nil, nil, ErrUnknownFlag},
+		{"missing value", withFlags, []string{"--limit"}, nil, nil, ErrMissingFlagValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positional, flags, err := tt.cmd.splitFlags(tt.args)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("splitFlags(%q) error = %v, want %v", tt.args, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitFlags(%q) error: %v", tt.args, err)
+			}
+			if !reflect.DeepEqual(positional, tt.positional) || !reflect.DeepEqual(flags, tt.flags) {
+				t.Errorf("splitFlags(%q) = %q, %v, want %q, %v", tt.args, positional, flags, tt.positional, tt.flags)
+			}
+		})
+	}
+}
+
+func TestUsageShowsConvertersOfTheRouter(t *testing.T) {
+	colorType := MessageCommandParamTypeCustom
+	r := NewMessageCommandRouter(nil)
+	r.RegisterConverter(colorType, &ConverterFunc{
+		TypeName: 	"color",
+		Func: func(ctx *MessageCommandContext, arg string) (interface{}, error) {
+			return arg, nil
+		},
+	})
+
+	sub := NewMessageCommand("set", "", nil, false, nil, nil, nil)
+	sub.AddFlags(&MessageCommandFlag{Name: "color", Type: colorType})
+	cmd := NewMessageCommand("theme", "", nil, false, nil, []*MessageCommand{sub}, nil)
+
+	want := "**theme**\n**theme set** [`--color <color>`]"
+	if got := cmd.usage(r.converters()); got != want {
+		t.Errorf("usage = %q, want %q", got, want)
+	}
+	for _, field := range r.CommandEmbed(cmd).Fields {
+		if field.Name == "Usage" && field.Value != want {
+			t.Errorf("usage in embed = %q, want %q", field.Value, want)
+		}
+	}
+
+	// Without a router the type has no name
+	if want := "**theme**\n**theme set** [`--color <value>`]"; cmd.Usage != want {
+		t.Errorf("Usage = %q, want %q", cmd.Usage, want)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Built-in help command
+//    help                     : menu of every category, with the commands the user can use
+//    help <category>          : commands of the category
+//    help <command> [sub...]  : details of the command or one of its subcommands
+func NewHelpCommand(r *MessageCommandRouter) *MessageCommand {
+	return NewMessageCommand(
+		"help",
+		"show the commands, a category or a command",
+		[]string{"help", "help fun", "help prefix set"},
+		true,
+		[]*MessageCommandParam{
+			{"name", MessageCommandParamTypeString, MessageCommandParamOptionOptional},
+			{"subcommands", MessageCommandParamTypeString, MessageCommandParamOptionList},
+		},
+		[]*MessageCommand{},
+		func(ctx *MessageCommandContext) error {
+			name, ok := ctx.ConvertedArgs["name"].(string)
+			categories := r.copyCategories()
+			if !ok {
+				return r.respondHelp(ctx, categories)
+			}
+
+			tmp := ctx.ConvertedArgs["subcommands"].([]interface{})
+			path := make([]string, len(tmp))
+			for i, v := range tmp {
+				path[i] = v.(string)
+			}
+
+			// Commands the user cannot use are treated as unknown
+			if cmd := r.GetCommand(name); cmd != nil {
+				if sub, rest := cmd.resolveSubCommand(path); len(rest) == 0 && r.canUse(ctx, sub) {
+					_, err := ctx.Respond(&discordgo.MessageSend{
+						Embed: r.CommandEmbed(sub),
+					})
+					return err
+				}
+			}
+
+			for _, c := range categories {
+				if strings.EqualFold(c.Name, name) && len(path) == 0 {
+					return r.respondHelp(ctx, []*MessageCommandCategory{c})
+				}
+			}
+
+			query := strings.Join(append([]string{name}, path...), " ")
+			if r.SuggestCommands {
+				if suggestions := r.suggestCommands(ctx, name); len(suggestions) != 0 {
+					_, err := ctx.RespondText("There is no command or category named `", query, "`. Did you mean `", strings.Join(suggestions, "`, `"), "`?")
+					return err
+				}
+			}
+			_, err := ctx.RespondText("There is no command or category named `", query, "`")
+			return err
+		},
+	)
+}
+
+// Limits of Discord
+const (
+	maxEmbedFields 			= 25
+	maxSelectMenuOptions 	= 25
+	maxSelectOptionLength 	= 100
+)
+
+// How long the help menu reacts to its components
+const helpMenuTimeout = 2 * time.Minute
+
+// CustomID of the select menu of the help menu
+const helpCategorySelect = "Category"
+
+// Copies of the categories with only the commands the user can use in ctx
+// Categories without any of those commands are left out
+func (r *MessageCommandRouter) visibleCategories(ctx *MessageCommandContext, categories []*MessageCommandCategory) []*MessageCommandCategory {
+	visible := []*MessageCommandCategory{}
+	for _, c := range categories {
+		cmds := []*MessageCommand{}
+		for _, cmd := range c.Item {
+			if r.canUse(ctx, cmd) {
+				cmds = append(cmds, cmd)
+			}
+		}
+		if len(cmds) == 0 {
+			continue
+		}
+		visible = append(visible, &MessageCommandCategory{
+			Name: 			c.Name,
+			Description: 	c.Description,
+			Emoji: 			c.Emoji,
+			Item: 			cmds,
+		})
+	}
+	return visible
+}
+
+// One page per category, categories too big for an embed are split into several pages
+// Return the pages and the index of the first page of each category
+func helpPages(categories []*MessageCommandCategory) (pages []*discordgo.MessageSend, first []int) {
+	for _, c := range categories {
+		first = append(first, len(pages))
+		for start := 0; start < len(c.Item); start += maxEmbedFields {
+			end := start + maxEmbedFields
+			if end > len(c.Item) {
+				end = len(c.Item)
+			}
+			page := &MessageCommandCategory{
+				Name: 			c.Name,
+				Description: 	c.Description,
+				Emoji: 			c.Emoji,
+				Item: 			c.Item[start:end],
+			}
+			pages = append(pages, &discordgo.MessageSend{
+				Embed: page.Embed(),
+			})
+		}
+	}
+	return pages, first
+}
+
+// Paginator showing the categories, with a select menu to jump to one of them
+// Only the invoking user can use it
+func (r *MessageCommandRouter) helpMenu(ctx *MessageCommandContext, categories []*MessageCommandCategory) *Paginator {
+	pages, first := helpPages(categories)
+
+	p := NewPaginator(ctx.Session, ctx.Message.ChannelID)
+	p.Add(pages...)
+	p.SetTimeout(helpMenuTimeout)
+	p.Widget.UserWhitelist = []string{ctx.Message.Author.ID}
+
+	options := []discordgo.SelectMenuOption{}
+	for i, c := range categories {
+		if i == maxSelectMenuOptions {
+			break
+		}
+		description := []rune(c.Description)
+		if len(description) > maxSelectOptionLength {
+			description = description[:maxSelectOptionLength]
+		}
+		options = append(options, discordgo.SelectMenuOption{
+			Label: 			c.Name,
+			Value: 			strconv.Itoa(first[i]),
+			Description: 	string(description),
+			Emoji: 			c.ComponentEmoji(),
+		})
+	}
+
+	// A select menu needs at least one option
+	if len(options) != 0 {
+		p.Widget.ExtraComponents = []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						CustomID: helpCategorySelect,
+						Placeholder: "Jump to a category",
+						Options: options,
+					},
+				},
+			},
+		}
+	}
+
+	p.Widget.AddHandler(helpCategorySelect, func(w *Widget, i *discordgo.Interaction) {
+		values := i.MessageComponentData().Values
+		if len(values) == 0 {
+			return
+		}
+		if index, err := strconv.Atoi(values[0]); err == nil && p.Goto(index) == nil {
+			p.Update(i)
+		}
+	})
+
+	return p
+}
+
+// Reply with the pages, as a plain embed if there is only one
+func (r *MessageCommandRouter) respondHelp(ctx *MessageCommandContext, categories []*MessageCommandCategory) error {
+	categories = r.visibleCategories(ctx, categories)
+	if len(categories) == 0 {
+		_, err := ctx.RespondText("There is no command you can use here")
+		return err
+	}
+
+	if pages, _ := helpPages(categories); len(pages) == 1 {
+		_, err := ctx.Respond(pages[0])
+		return err
+	}
+
+	// The menu waits for interactions until it times out, do not hold a worker meanwhile
+	menu := r.helpMenu(ctx, categories)
+	ctx.Detach(func(ctx *MessageCommandContext) error {
+		return menu.DeployContext(ctx.Context())
+	})
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestHelpPages(t *testing.T) {
+	big := &MessageCommandCategory{Name: "Big"}
+	for i := 0; i < 30; i++ {
+		big.Item = append(big.Item, NewMessageCommand(fmt.Sprint("cmd", i), "", nil, true, nil, nil, nil))
+	}
+	small := &MessageCommandCategory{Name: "Small", Item: big.Item[:2]}
+
+	pages, first := helpPages([]*MessageCommandCategory{big, small})
+	if len(pages) != 3 {
+		t.Fatalf("%d pages, want 3", len(pages))
+	}
+	if first[0] != 0 || first[1] != 2 {
+		t.Errorf("first pages = %v, want [0 2]", first)
+	}
+	if n := len(pages[1].Embed.Fields); n != 5 {
+		t.Errorf("second page of Big has %d fields, want 5", n)
+	}
+}
+
+func TestVisibleCategories(t *testing.T) {
+	r := NewMessageCommandRouter(nil)
+	r.Overrides = NewMemoryOverrideStore()
+	r.AddCategory(&MessageCommandCategory{Name: "Fun"}, &MessageCommandCategory{Name: "Admin"})
+
+	roll := NewMessageCommand("roll", "", nil, true, nil, nil, nil)
+	roll.Category = "Fun"
+	joke := NewMessageCommand("joke", "", nil, true, nil, nil, nil)
+	joke.Category = "Fun"
+	ban := NewMessageCommand("ban", "", nil, true, nil, nil, nil)
+	ban.Category = "Admin"
+	ban.OwnerOnly = true
+	for _, cmd := range []*MessageCommand{roll, joke, ban} {
+		if err := r.AddCommand(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Overrides.SetOverride(&CommandOverride{GuildID: "1", Target: "joke"})
+
+	ctx := &MessageCommandContext{
+		Router: 	r,
+		Message: 	&discordgo.Message{GuildID: "1", ChannelID: "10", Author: &discordgo.User{ID: "1"}},
+	}
+	visible := r.visibleCategories(ctx, r.copyCategories())
+	if len(visible) != 1 || visible[0].Name != "Fun" || len(visible[0].Item) != 1 || visible[0].Item[0] != roll {
+		t.Fatalf("visible categories = %+v, want Fun with roll only", visible)
+	}
+}
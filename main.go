@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
@@ -14,6 +15,7 @@ import (
 var (
 	r *MessageCommandRouter
 	s *discordgo.Session
+	p *StorePrefixResolver
 )
 
 func init() {
@@ -22,29 +24,44 @@ func init() {
 		panic(err)
 	}
 	r = NewMessageCommandRouter([]string{"t."})
+	p = NewStorePrefixResolver(NewMemoryPrefixStore(), []string{"t."})
+	r.PrefixResolver = p
+	r.MentionPrefix = true
+	r.DMWithoutPrefix = true
+	r.SuggestCommands = true
+	r.RerunOnEdit = true
+	r.DeleteResponses = true
+	r.Executor = NewExecutor(16, 64, ExecutorOverflowReject)
 }
 
 func init() {
-	r.AddCommand(NewMessageCommand(
+	r.AddCategory(
+		&MessageCommandCategory{Name: "Fun", Description: "random things", Emoji: "🎲"},
+		&MessageCommandCategory{Name: "Utility", Description: "bot settings and help", Emoji: "🔧"},
+	)
+
+	random := NewMessageCommand(
 		"random",
 		"randomly pick from a sequence",
 		[]string{"random ligma sawcon"},
 		true,
 		[]*MessageCommandParam{{"strings", MessageCommandParamTypeUser, MessageCommandParamOptionList}},
 		[]*MessageCommand{},
-		func(ctx *MessageCommandContext) {
+		func(ctx *MessageCommandContext) error {
 			tmp := ctx.ConvertedArgs["strings"].([]interface{})
 			li := make([]*discordgo.User, len(tmp))
 			for i, v := range tmp {
 				li[i] = v.(*discordgo.User)
 			}
-			ctx.Respond(&discordgo.MessageSend{
+			_, err := ctx.Respond(&discordgo.MessageSend{
 				Content: fmt.Sprintf("%+v", li[rand.Intn(len(li))].AvatarURL("")),
 			})
+			return err
 		},
-	))
+	)
+	random.Category = "Fun"
 
-	r.AddCommand(NewMessageCommand(
+	randrange := NewMessageCommand(
 		"randrange",
 		"random a range",
 		[]string{"randrange 1 100"},
@@ -54,34 +71,45 @@ func init() {
 			{"second", MessageCommandParamTypeInteger, MessageCommandParamOptionRequired},
 		},
 		[]*MessageCommand{},
-		func(ctx *MessageCommandContext) {
+		func(ctx *MessageCommandContext) error {
 			first, second := ctx.ConvertedArgs["first"].(int), ctx.ConvertedArgs["second"].(int)
 			rand.Seed(time.Now().UnixNano())
 			val := rand.Intn(second-first+1) + first
-			ctx.RespondText(val)
+			_, err := ctx.RespondText(val)
+			return err
 		},
-	))
+	)
+	randrange.Cooldown = &Cooldown{2, 10 * time.Second, CommandBucketUser, nil}
+	randrange.Category = "Fun"
 
-	r.AddCommand(NewMessageCommand(
-		"help",
-		"use help",
-		[]string{"help games"},
-		true,
-		[]*MessageCommandParam{{"command", MessageCommandParamTypeString, MessageCommandParamOptionRequired}},
-		[]*MessageCommand{},
-		func(ctx *MessageCommandContext) {
-			e := r.GetCommand(ctx.ConvertedArgs["command"].(string)).Embed()
+	help := NewHelpCommand(r)
+	help.Category = "Utility"
 
-			ctx.Respond(&discordgo.MessageSend{
-				Embed: e,
-			})
-		},
-	))
+	prefix := NewPrefixCommand(p)
+	prefix.Category = "Utility"
+
+	r.Overrides = NewMemoryOverrideStore()
+	overrides := NewOverrideCommands(r, r.Overrides)
+	for _, cmd := range overrides {
+		cmd.Category = "Utility"
+	}
+
+	for _, cmd := range append([]*MessageCommand{random, randrange, help, prefix}, overrides...) {
+		if err := r.AddCommand(cmd); err != nil {
+			panic(err)
+		}
+	}
 
 	s.AddHandler(r.Handler())
+	s.AddHandler(r.EditHandler())
+	s.AddHandler(r.DeleteHandler())
+	s.AddHandler(r.DeleteBulkHandler())
 }
 
 func main() {
+	// Commands still running are cancelled on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	r.RootContext = ctx
 
 	if err := s.Open(); err != nil {
 		panic(err)
@@ -93,6 +121,7 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt)
 	<-stop
+	cancel()
 
 	log.Println("Disconnected")
 }
@@ -2,8 +2,10 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -25,7 +27,6 @@ func NewMessageCommand(
 	cmd.Name = name
 	cmd.Description = description
 	cmd.Examples = examples
-	cmd.SubCommands = subcommands
 	cmd.Handler = handler
 	cmd.IgnoreCase = ignoreCase
 	
@@ -47,12 +48,14 @@ func NewMessageCommand(
 	}
 	cmd.Params = params
 
-	// Initialize Usage field
-	s := "**" + name + "**"
-	for _, p := range params {
-		s += " `" + p.Name + "`"
+	// Attach subcommands to this command
+	for _, sub := range subcommands {
+		sub.Parent = cmd
 	}
-	cmd.Usage = s
+	cmd.SubCommands = subcommands
+
+	// Initialize Usage field of the whole tree
+	cmd.generateUsage()
 
 	return cmd
 }
@@ -60,7 +63,8 @@ func NewMessageCommand(
 // Event type for message
 type MessageCommandEvent func(s *discordgo.Session, m *discordgo.MessageCreate)
 
-type MessageCommandHandler func(ctx *MessageCommandContext)
+// Returned errors are passed to the OnError hook of the router
+type MessageCommandHandler func(ctx *MessageCommandContext) error
 
 type MessageCommandParamType uint8
 
@@ -77,6 +81,15 @@ const (
 	// MessageCommandParamTypeMentionable		MessageCommandParamType = 7
 	// MessageCommandParamTypeSubCommand		MessageCommandParamType = 8
 	// MessageCommandParamTypeSubCommandGroup 	MessageCommandParamType = 9
+	MessageCommandParamTypeFloat 			MessageCommandParamType = 10
+	MessageCommandParamTypeDuration 		MessageCommandParamType = 11
+	MessageCommandParamTypeTimestamp 		MessageCommandParamType = 12
+	MessageCommandParamTypeMember 			MessageCommandParamType = 13
+	MessageCommandParamTypeEmoji 			MessageCommandParamType = 14
+	MessageCommandParamTypeMessage 			MessageCommandParamType = 15
+
+	// First value free for custom types, see ArgumentConverter
+	MessageCommandParamTypeCustom 			MessageCommandParamType = 128
 )
 
 // Enum for Param Option
@@ -95,6 +108,11 @@ type MessageCommandParam struct {
 }
 
 func (p *MessageCommandParam) OptionType() string {
+	return p.optionType(DefaultConverters)
+}
+
+// Same as OptionType with type names from the converters
+func (p *MessageCommandParam) optionType(converters *ConverterRegistry) string {
 	var option string
 	switch p.Option {
 	case MessageCommandParamOptionRequired:
@@ -104,29 +122,15 @@ func (p *MessageCommandParam) OptionType() string {
 	case MessageCommandParamOptionList:
 		option = "List[%s]"
 	default:
-		panic("There is no such Option")
-	}
-
-	var t string
-
-	switch p.Type {
-	case MessageCommandParamTypeString:
-		t = "`string`"
-	case MessageCommandParamTypeInteger:
-		t = "`int`"
-	case MessageCommandParamTypeBoolean:
-		t = "`boolean`"
-	case MessageCommandParamTypeUser:
-		t = "`user`"
-	case MessageCommandParamTypeRole:
-		t = "`role`"
-	case MessageCommandParamTypeChannel:
-		t = "`channel`"
-	default:
-		panic("There is no such Type")
+		option = "%s"
 	}
 
-	return fmt.Sprintf(option, t)
+	return fmt.Sprintf(option, "`" + converters.typeName(p.Type) + "`")
+}
+
+// Name of the type displayed to users, from DefaultConverters
+func (t MessageCommandParamType) String() string {
+	return DefaultConverters.typeName(t)
 }
 
 type MessageCommand struct {
@@ -136,20 +140,180 @@ type MessageCommand struct {
 	Aliases 	[]string
 	IgnoreCase 	bool
 	Description string
+	// Generated with the type names of DefaultConverters
+	// Help and errors of a router show the type names of its own converters instead
 	Usage		string
 	Examples	[]string
 
+	// Name of the category the command is listed in, DefaultCategoryName if empty
+	// Only used for top level commands
+	Category 	string
+
 	SubCommands []*MessageCommand
 
+	// The command this one is nested in, nil for top level commands
+	Parent 		*MessageCommand
+
+	// Named options given anywhere in the arguments, see AddFlags
+	Flags 		[]*MessageCommandFlag
+
+	// Permissions the user needs in the channel, only checked in servers
+	Permissions 	int64
+	// Permissions the bot needs in the channel, only checked in servers
+	BotPermissions 	int64
+
+	GuildOnly 	bool
+	DMOnly 		bool
+	OwnerOnly 	bool
+	NSFWOnly 	bool
+
+	// Custom checks, run after the built-in ones
+	// Checks of a command also apply to its subcommands
+	Checks 		[]MessageCommandCheck
+
+	// Ignore overrides of the router, for commands managing them
+	alwaysEnabled 	bool
+
+	// Limit how often the command can be used, nil for no limit
+	Cooldown 	*Cooldown
+
+	// Limit how many uses of the command run at once, nil for no limit
+	MaxConcurrency *MaxConcurrency
+
+	// Cancel the context of the handler after this long, 0 for no limit
+	// Subcommands without timeout use the one of their parent
+	Timeout 	time.Duration
+
 	// Command Handler
 	// 
 	Handler 	MessageCommandHandler
+
+	// Middlewares applied to this command only
+	// Executed in the order they were added, after the router's middlewares
+	Middlewares []MessageCommandMiddleware
+}
+
+// Name of the command prefixed by the names of its parents
+func (cmd *MessageCommand) FullName() string {
+	if cmd.Parent == nil {
+		return cmd.Name
+	}
+	return cmd.Parent.FullName() + " " + cmd.Name
+}
+
+// Get the direct subcommand matching name or one of its aliases
+func (cmd *MessageCommand) GetSubCommand(name string) *MessageCommand {
+	for _, sub := range cmd.SubCommands {
+		if sub.matchName(name) {
+			return sub
+		}
+	}
+	return nil
+}
+
+func (cmd *MessageCommand) matchName(name string) bool {
+	for _, n := range append([]string{cmd.Name}, cmd.Aliases...) {
+		if n == name || (cmd.IgnoreCase && strings.EqualFold(n, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk down the subcommand tree following the leading arguments
+// Return the deepest matched command and the arguments left for it
+func (cmd *MessageCommand) resolveSubCommand(arguments []string) (*MessageCommand, []string) {
+	for len(arguments) > 0 {
+		sub := cmd.GetSubCommand(arguments[0])
+		if sub == nil {
+			break
+		}
+		cmd, arguments = sub, arguments[1:]
+	}
+	return cmd, arguments
+}
+
+// Middlewares of every command from the root down to this one
+func (cmd *MessageCommand) middlewareChain() []MessageCommandMiddleware {
+	if cmd.Parent == nil {
+		return cmd.Middlewares
+	}
+	parent := cmd.Parent.middlewareChain()
+	middlewares := make([]MessageCommandMiddleware, 0, len(parent) + len(cmd.Middlewares))
+	middlewares = append(middlewares, parent...)
+	return append(middlewares, cmd.Middlewares...)
+}
+
+// Timeout of the command or of its closest parent having one
+func (cmd *MessageCommand) timeout() time.Duration {
+	for c := cmd; c != nil; c = c.Parent {
+		if c.Timeout != 0 {
+			return c.Timeout
+		}
+	}
+	return 0
+}
+
+// Return an InvalidCommandError if the command or one of its subcommands is misconfigured
+func (cmd *MessageCommand) validate() error {
+	if cmd.Cooldown != nil {
+		if err := cmd.Cooldown.validate(); err != nil {
+			return &InvalidCommandError{cmd, err}
+		}
+	}
+	if cmd.MaxConcurrency != nil {
+		if err := cmd.MaxConcurrency.validate(); err != nil {
+			return &InvalidCommandError{cmd, err}
+		}
+	}
+	for _, sub := range cmd.SubCommands {
+		if err := sub.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Generate usage of the command and all of its subcommands
+func (cmd *MessageCommand) generateUsage() {
+	for _, sub := range cmd.SubCommands {
+		sub.generateUsage()
+	}
+	cmd.Usage = cmd.usage(DefaultConverters)
+}
+
+// Usage of the command and all of its subcommands with type names from the converters
+func (cmd *MessageCommand) usage(converters *ConverterRegistry) string {
+	s := "**" + cmd.FullName() + "**"
+	for _, p := range cmd.Params {
+		s += " `" + p.Name + "`"
+	}
+	for _, f := range cmd.Flags {
+		s += " " + f.usage(converters)
+	}
+	for _, sub := range cmd.SubCommands {
+		s += "\n" + sub.usage(converters)
+	}
+	return s
+}
+
+// Add middlewares to the command
+func (cmd *MessageCommand) Use(middlewares ...MessageCommandMiddleware) {
+	cmd.Middlewares = append(cmd.Middlewares, middlewares...)
 }
 
 func (cmd *MessageCommand) Embed() *discordgo.MessageEmbed {
+	return cmd.embed(DefaultConverters)
+}
+
+// Same as Embed with type names from the converters
+func (cmd *MessageCommand) embed(converters *ConverterRegistry) *discordgo.MessageEmbed {
 	param := ""
 	for _, p := range cmd.Params {
-		param += fmt.Sprintf("`%s`: ", p.Name) + p.OptionType() + "\n"
+		param += fmt.Sprintf("`%s`: ", p.Name) + p.optionType(converters) + "\n"
+	}
+	if param == "" {
+		param = "None"
 	}
 
 	f := []*discordgo.MessageEmbedField{
@@ -160,7 +324,7 @@ func (cmd *MessageCommand) Embed() *discordgo.MessageEmbed {
 		},
 		{
 			Name: "Usage",
-			Value: cmd.Usage,
+			Value: cmd.usage(converters),
 			Inline: false,
 		},
 		{
@@ -174,17 +338,39 @@ func (cmd *MessageCommand) Embed() *discordgo.MessageEmbed {
 		},
 	}
 
+	if len(cmd.Flags) != 0 {
+		flag := ""
+		for _, fl := range cmd.Flags {
+			flag += fl.help(converters) + "\n"
+		}
+		// Show flags right after arguments
+		f = append(f[:3], append([]*discordgo.MessageEmbedField{{
+			Name: "Flag(s)",
+			Value: flag,
+			Inline: false,
+		}}, f[3:]...)...)
+	}
+
+	if len(cmd.SubCommands) != 0 {
+		sub := ""
+		for _, c := range cmd.SubCommands {
+			sub += fmt.Sprintf("`%s`: %s\n", c.Name, c.Description)
+		}
+		f = append(f, &discordgo.MessageEmbedField{
+			Name: "Subcommand(s)",
+			Value: sub,
+			Inline: false,
+		})
+	}
+
 	return &discordgo.MessageEmbed{
-		Title: cmd.Name + " COMMAND",
+		Title: cmd.FullName() + " COMMAND",
 		Fields: f,
 	}
 }
 
-func (cmd *MessageCommand) ValidateArguments(arguments []string) bool {
-	l := len(arguments)
-	
-	var minArg, maxArg int
-
+// Get min and max number of arguments, max is -1 if the last param is a List
+func (cmd *MessageCommand) argumentBounds() (minArg int, maxArg int) {
 	// Get min arguments
 	for i, p := range cmd.Params {
 		if p.Option == MessageCommandParamOptionOptional {
@@ -195,39 +381,58 @@ func (cmd *MessageCommand) ValidateArguments(arguments []string) bool {
 
 	// Get max arguments
 	pl := len(cmd.Params)
-	if pl == 0 {
-		maxArg = pl
-	} else if cmd.Params[pl-1].Option == MessageCommandParamOptionList {
-		maxArg = 2000
+	if pl != 0 && cmd.Params[pl-1].Option == MessageCommandParamOptionList {
+		maxArg = -1
 	} else {
 		maxArg = pl
 	}
 
-	if l >= minArg && l <= maxArg {
-		return true
-	}
+	return minArg, maxArg
+}
 
-	return false
+func (cmd *MessageCommand) ValidateArguments(arguments []string) bool {
+	l := len(arguments)
+	minArg, maxArg := cmd.argumentBounds()
+
+	return l >= minArg && (maxArg == -1 || l <= maxArg)
+}
+
+// Same as ValidateArguments but return an ArgumentCountError if not valid
+func (cmd *MessageCommand) checkArguments(arguments []string) error {
+	if cmd.ValidateArguments(arguments) {
+		return nil
+	}
+	minArg, maxArg := cmd.argumentBounds()
+	return &ArgumentCountError{cmd, len(arguments), minArg, maxArg}
 }
 
+// Convert the arguments with the converters of the router of ctx
+// Return a ConversionError if an argument cannot be converted
 func (cmd *MessageCommand) ConvertArguments(
-	s *discordgo.Session, 
-	m *discordgo.Message, 
+	ctx *MessageCommandContext,
 	arguments []string,
 ) (convertedArgs map[string]interface{}, err error) {
 	paramMap := make(map[string]interface{})
+	converters := ctx.converters()
 
 	for i, p := range cmd.Params {
 		if p.Option == MessageCommandParamOptionOptional || p.Option == MessageCommandParamOptionRequired {
-			if res, err := argumentsConverter(s, m, arguments[i], p.Type); err != nil {
+			if i >= len(arguments) {
+				break
+			}
+			if res, err := converters.convert(ctx, p.Name, arguments[i], p.Type); err != nil {
 				return nil, err
 			} else {
 				paramMap[p.Name] = res
 			}
 		} else if p.Option == MessageCommandParamOptionList {
+			if i >= len(arguments) {
+				paramMap[p.Name] = []interface{}{}
+				break
+			}
 			li := make([]interface{}, len(arguments) - i)
 			for j := 0; j < len(arguments) - i; j++ {
-				if res, err := argumentsConverter(s, m, arguments[i + j], p.Type); err != nil {
+				if res, err := converters.convert(ctx, p.Name, arguments[i + j], p.Type); err != nil {
 					return nil, err
 				} else {
 					li[j] = res
@@ -253,29 +458,53 @@ func (m *MessageCommandMap) Init() {
 	}
 }
 
-func (m *MessageCommandMap) Set(cmd *MessageCommand) {
+// Keys of the command in the map, its name and aliases, lower case if it ignores case
+func (cmd *MessageCommand) keys() []string {
+	keys := append([]string{cmd.Name}, cmd.Aliases...)
+	if cmd.IgnoreCase {
+		for i, key := range keys {
+			keys[i] = strings.ToLower(key)
+		}
+	}
+	return keys
+}
+
+// Return a CommandExistsError if a key of cmd is used by another command of mapping
+// Keys are compared case insensitively if either command ignores case, both would match the same names
+func checkCollision(mapping map[string]*MessageCommand, cmd *MessageCommand) error {
+	for _, key := range cmd.keys() {
+		for k, existing := range mapping {
+			if existing == cmd {
+				continue
+			}
+			if k == key || ((cmd.IgnoreCase || existing.IgnoreCase) && strings.EqualFold(k, key)) {
+				return &CommandExistsError{key, existing, cmd}
+			}
+		}
+	}
+	return nil
+}
+
+// Add the command under its name and aliases
+// Return a CommandExistsError and add nothing if one of them is used by another command
+func (m *MessageCommandMap) Set(cmd *MessageCommand) error {
 	m.Lock()
 	defer m.Unlock()
 
 	m.Init()
-	if cmd.IgnoreCase {
-		m.Map[strings.ToLower(cmd.Name)] = cmd
-		for _, alias := range cmd.Aliases {
-			m.Map[strings.ToLower(alias)] = cmd
-		}
-	} else {
-		m.Map[cmd.Name] = cmd
-		for _, alias := range cmd.Aliases {
-			m.Map[alias] = cmd
-		}
+	if err := checkCollision(m.Map, cmd); err != nil {
+		return err
+	}
+	for _, key := range cmd.keys() {
+		m.Map[key] = cmd
 	}
+	return nil
 }
 
 func (m *MessageCommandMap) Get(name string) *MessageCommand {
 	m.RLock()
 	defer m.RUnlock()
 
-	m.Init()
 	if c, ok := m.Map[name]; ok {
 		return c
 	}
@@ -283,10 +512,67 @@ func (m *MessageCommandMap) Get(name string) *MessageCommand {
 	return nil
 }
 
+// Remove a single key, the other keys of the command are kept
+// Use RemoveCommand to remove a command entirely
 func (m *MessageCommandMap) Del(name string) {
 	m.Lock()
 	defer m.Unlock()
 
 	m.Init()
 	delete(m.Map, name)
-}
\ No newline at end of file
+}
+
+// Remove every key of the command, return false if the command was not in the map
+func (m *MessageCommandMap) RemoveCommand(cmd *MessageCommand) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	// Look at the values, the aliases may have changed since the command was added
+	removed := false
+	for key, c := range m.Map {
+		if c == cmd {
+			delete(m.Map, key)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// Get every command once, sorted by name
+func (m *MessageCommandMap) Commands() []*MessageCommand {
+	m.RLock()
+	defer m.RUnlock()
+
+	seen := make(map[*MessageCommand]bool)
+	cmds := []*MessageCommand{}
+	for _, cmd := range m.Map {
+		if !seen[cmd] {
+			seen[cmd] = true
+			cmds = append(cmds, cmd)
+		}
+	}
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].Name < cmds[j].Name
+	})
+	return cmds
+}
+
+// Replace every command of the map at once, readers never see a partial set
+// Return a CommandExistsError and keep the current commands if two of them collide
+func (m *MessageCommandMap) ReplaceAll(cmds []*MessageCommand) error {
+	mapping := make(map[string]*MessageCommand)
+	for _, cmd := range cmds {
+		if err := checkCollision(mapping, cmd); err != nil {
+			return err
+		}
+		for _, key := range cmd.keys() {
+			mapping[key] = cmd
+		}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.Map = mapping
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func mapCommand(name string, ignoreCase bool, aliases ...string) *MessageCommand {
+	cmd := NewMessageCommand(name, "", nil, ignoreCase, nil, nil, nil)
+	cmd.Aliases = aliases
+	return cmd
+}
+
+func TestCheckCollision(t *testing.T) {
+	tests := []struct {
+		name 		string
+		existing 	*MessageCommand
+		cmd 		*MessageCommand
+		key 		string
+	}{
+		{"same name", mapCommand("ping", true), mapCommand("ping", true), "ping"},
+		{"alias on a name", mapCommand("ping", true), mapCommand("pong", true, "PING"), "ping"},
+		{"new one ignores case", mapCommand("Ping", false), mapCommand("ping", true), "ping"},
+		{"existing one ignores case", mapCommand("ping", true), mapCommand("Ping", false), "Ping"},
+		{"both case sensitive", mapCommand("Ping", false), mapCommand("Ping", false, "p"), "Ping"},
+		{"case sensitive names differ", mapCommand("Ping", false), mapCommand("ping", false), ""},
+		{"different names", mapCommand("ping", true), mapCommand("pong", true), ""},
+	}
+
+	for _, tt := range tests {
+		var m MessageCommandMap
+		if err := m.Set(tt.existing); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		err := m.Set(tt.cmd)
+		if tt.key == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		var exists *CommandExistsError
+		if !errors.As(err, &exists) {
+			t.Errorf("%s: error = %v, want a CommandExistsError", tt.name, err)
+			continue
+		}
+		if exists.Key != tt.key || exists.Existing != tt.existing || exists.Command != tt.cmd {
+			t.Errorf("%s: error = %+v, want key %q", tt.name, exists, tt.key)
+		}
+		// Nothing of the rejected command is added
+		if cmds := m.Commands(); len(cmds) != 1 || cmds[0] != tt.existing {
+			t.Errorf("%s: commands = %v, want only the existing one", tt.name, cmds)
+		}
+	}
+}
+
+func TestMessageCommandMapRemoveCommand(t *testing.T) {
+	var m MessageCommandMap
+	roll := mapCommand("roll", true, "r", "dice")
+	ping := mapCommand("ping", true)
+	for _, cmd := range []*MessageCommand{roll, ping} {
+		if err := m.Set(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !m.RemoveCommand(roll) {
+		t.Fatal("roll is not removed")
+	}
+	for _, key := range []string{"roll", "r", "dice"} {
+		if m.Get(key) != nil {
+			t.Errorf("%s is kept after removing roll", key)
+		}
+	}
+	if m.Get("ping") != ping {
+		t.Error("ping is removed with roll")
+	}
+	if m.RemoveCommand(roll) {
+		t.Error("removing roll twice succeeded")
+	}
+}
+
+func TestMessageCommandMapCommands(t *testing.T) {
+	var m MessageCommandMap
+	for _, cmd := range []*MessageCommand{
+		mapCommand("roll", true, "r"), mapCommand("ban", true), mapCommand("ping", true, "p", "latency"),
+	} {
+		if err := m.Set(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every command once, in the same order each time
+	for i := 0; i < 10; i++ {
+		cmds := m.Commands()
+		if len(cmds) != 3 || cmds[0].Name != "ban" || cmds[1].Name != "ping" || cmds[2].Name != "roll" {
+			t.Fatalf("commands = %v, want ban, ping and roll", cmds)
+		}
+	}
+}
+
+func TestMessageCommandMapReplaceAll(t *testing.T) {
+	var m MessageCommandMap
+	roll := mapCommand("roll", true)
+	if err := m.Set(roll); err != nil {
+		t.Fatal(err)
+	}
+
+	ping := mapCommand("ping", true)
+	pong := mapCommand("pong", true, "Ping")
+	var exists *CommandExistsError
+	if err := m.ReplaceAll([]*MessageCommand{ping, pong}); !errors.As(err, &exists) || exists.Existing != ping {
+		t.Fatalf("error = %v, want a CommandExistsError with ping", err)
+	}
+	if cmds := m.Commands(); len(cmds) != 1 || cmds[0] != roll {
+		t.Fatalf("commands = %v after a collision, want roll only", cmds)
+	}
+
+	if err := m.ReplaceAll([]*MessageCommand{ping}); err != nil {
+		t.Fatal(err)
+	}
+	if m.Get("roll") != nil || m.Get("ping") != ping {
+		t.Error("commands are not replaced")
+	}
+}
+
+func TestReplaceCommands(t *testing.T) {
+	r := NewMessageCommandRouter(nil)
+	r.AddCategory(&MessageCommandCategory{Name: "Fun"}, &MessageCommandCategory{Name: "Admin"})
+
+	roll := mapCommand("roll", true)
+	roll.Category = "Fun"
+	ban := mapCommand("ban", true)
+	ban.Category = "Admin"
+	for _, cmd := range []*MessageCommand{roll, ban} {
+		if err := r.AddCommand(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	joke := mapCommand("joke", true)
+	joke.Category = "Fun"
+	kick := mapCommand("kick", true)
+	if err := r.ReplaceCommands([]*MessageCommand{joke, kick}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Categories are kept and only hold the new commands
+	want := map[string][]*MessageCommand{
+		"Fun": 					{joke},
+		"Admin": 				{},
+		DefaultCategoryName: 	{kick},
+	}
+	for name, items := range want {
+		c := r.GetCategory(name)
+		if c == nil {
+			t.Errorf("category %s is missing", name)
+			continue
+		}
+		if len(c.Item) != len(items) || (len(items) != 0 && c.Item[0] != items[0]) {
+			t.Errorf("category %s holds %v, want %v", name, c.Item, items)
+		}
+	}
+	if r.GetCommand("roll") != nil || r.GetCommand("joke") != joke {
+		t.Error("commands of the router are not replaced")
+	}
+}
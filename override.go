@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Enable or disable a command or a category in a server or one of its channels
+// Channel overrides win over server overrides, and commands win over their category
+type CommandOverride struct {
+	GuildID 	string
+
+	// Empty for the whole server
+	ChannelID 	string
+
+	// Full name of the command, or name of the category
+	Target 		string
+	IsCategory 	bool
+
+	Enabled 	bool
+}
+
+func (o *CommandOverride) sameTarget(channelID string, target string, isCategory bool) bool {
+	return o.ChannelID == channelID && strings.EqualFold(o.Target, target) && o.IsCategory == isCategory
+}
+
+// Storage for the overrides of servers
+type CommandOverrideStore interface {
+	GetOverrides(guildID string) ([]*CommandOverride, error)
+	// Replace the override with the same channel and target
+	SetOverride(o *CommandOverride) error
+	DeleteOverride(guildID string, channelID string, target string, isCategory bool) error
+}
+
+// In memory CommandOverrideStore, overrides are lost on restart
+type MemoryOverrideStore struct {
+	// To provide thread safe access endpoints
+	sync.RWMutex
+	Map 	map[string][]*CommandOverride
+}
+
+func NewMemoryOverrideStore() *MemoryOverrideStore {
+	return &MemoryOverrideStore{
+		Map: make(map[string][]*CommandOverride),
+	}
+}
+
+func (ms *MemoryOverrideStore) GetOverrides(guildID string) ([]*CommandOverride, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	return append([]*CommandOverride{}, ms.Map[guildID]...), nil
+}
+
+func (ms *MemoryOverrideStore) SetOverride(o *CommandOverride) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	overrides := ms.Map[o.GuildID]
+	for i, old := range overrides {
+		if old.sameTarget(o.ChannelID, o.Target, o.IsCategory) {
+			overrides[i] = o
+			return nil
+		}
+	}
+	ms.Map[o.GuildID] = append(overrides, o)
+	return nil
+}
+
+func (ms *MemoryOverrideStore) DeleteOverride(guildID string, channelID string, target string, isCategory bool) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	overrides := ms.Map[guildID]
+	for i, old := range overrides {
+		if old.sameTarget(channelID, target, isCategory) {
+			ms.Map[guildID] = append(overrides[:i:i], overrides[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Target of an override
+type overrideTarget struct {
+	name 		string
+	isCategory 	bool
+}
+
+// Targets affecting the command from the most to the least specific
+// The command, its parents, then the category of the top level command
+func (r *MessageCommandRouter) overrideTargets(cmd *MessageCommand) []overrideTarget {
+	targets := []overrideTarget{}
+	root := cmd
+	for c := cmd; c != nil; c = c.Parent {
+		targets = append(targets, overrideTarget{c.FullName(), false})
+		root = c
+	}
+	if root.Category != "" {
+		targets = append(targets, overrideTarget{root.Category, true})
+	}
+	return targets
+}
+
+// Whether the targets are enabled in the channel according to the overrides
+// The most specific override of the channel wins, then the one of the server
+func overridesEnabled(overrides []*CommandOverride, channelID string, targets []overrideTarget) bool {
+	for _, scope := range []string{channelID, ""} {
+		for _, t := range targets {
+			for _, o := range overrides {
+				if o.sameTarget(scope, t.name, t.isCategory) {
+					return o.Enabled
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Return a CommandDisabledError if the command is disabled where the message was sent
+func (r *MessageCommandRouter) checkOverrides(cmd *MessageCommand, m *discordgo.Message) error {
+	if r.Overrides == nil || m.GuildID == "" {
+		return nil
+	}
+
+	// Commands managing overrides cannot be disabled, or nobody could enable anything again
+	for c := cmd; c != nil; c = c.Parent {
+		if c.alwaysEnabled {
+			return nil
+		}
+	}
+
+	overrides, err := r.Overrides.GetOverrides(m.GuildID)
+	if err != nil {
+		return err
+	}
+	if !overridesEnabled(overrides, m.ChannelID, r.overrideTargets(cmd)) {
+		return &CommandDisabledError{cmd, m.ChannelID}
+	}
+	return nil
+}
+
+// Find the command (words separated by spaces for subcommands) or the category named name
+// Also return the targets affecting it, see overrideTargets
+func (r *MessageCommandRouter) findOverrideTarget(name string) (target overrideTarget, targets []overrideTarget, ok bool) {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return target, nil, false
+	}
+	if cmd := r.GetCommand(words[0]); cmd != nil {
+		if sub, rest := cmd.resolveSubCommand(words[1:]); len(rest) == 0 {
+			targets = r.overrideTargets(sub)
+			return targets[0], targets, true
+		}
+	}
+	if c := r.GetCategory(name); c != nil {
+		target = overrideTarget{c.Name, true}
+		return target, []overrideTarget{target}, true
+	}
+	return target, nil, false
+}
+
+func (o *CommandOverride) String() string {
+	state := "disabled"
+	if o.Enabled {
+		state = "enabled"
+	}
+	kind := "command"
+	if o.IsCategory {
+		kind = "category"
+	}
+	where := "the whole server"
+	if o.ChannelID != "" {
+		where = "<#" + o.ChannelID + ">"
+	}
+	return fmt.Sprintf("%s `%s` %s in %s", kind, o.Target, state, where)
+}
+
+// Built-in commands to enable and disable commands and categories in a server or a channel
+//    disable <command|category> [channel]
+//    enable <command|category> [channel]
+//    overrides
+// Subcommands are written with spaces and quoted, like "prefix set"
+// They require Manage Server permission and cannot be disabled themselves
+func NewOverrideCommands(r *MessageCommandRouter, store CommandOverrideStore) []*MessageCommand {
+	params := []*MessageCommandParam{
+		{"name", MessageCommandParamTypeString, MessageCommandParamOptionRequired},
+		{"channel", MessageCommandParamTypeChannel, MessageCommandParamOptionOptional},
+	}
+
+	// Get the target, the targets affecting it and the channel, empty for the whole server
+	parse := func(ctx *MessageCommandContext) (overrideTarget, []overrideTarget, string, error) {
+		name := ctx.ConvertedArgs["name"].(string)
+		target, targets, ok := r.findOverrideTarget(name)
+		if !ok {
+			return target, nil, "", fmt.Errorf("There is no command or category named `%s`", name)
+		}
+		channelID := ""
+		if c, ok := ctx.ConvertedArgs["channel"].(*discordgo.Channel); ok {
+			channelID = c.ID
+		}
+		return target, targets, channelID, nil
+	}
+
+	disable := NewMessageCommand(
+		"disable",
+		"disable a command or a category in this server or in a channel",
+		[]string{"disable randrange", "disable fun #general", "disable \"prefix set\""},
+		true,
+		params,
+		[]*MessageCommand{},
+		func(ctx *MessageCommandContext) error {
+			target, _, channelID, err := parse(ctx)
+			if err != nil {
+				_, err = ctx.RespondText(err.Error())
+				return err
+			}
+			o := &CommandOverride{ctx.Message.GuildID, channelID, target.name, target.isCategory, false}
+			if err := store.SetOverride(o); err != nil {
+				return err
+			}
+			_, err = ctx.RespondText(o.String())
+			return err
+		},
+	)
+
+	enable := NewMessageCommand(
+		"enable",
+		"enable a command or a category in this server or in a channel",
+		[]string{"enable randrange", "enable fun #general"},
+		true,
+		params,
+		[]*MessageCommand{},
+		func(ctx *MessageCommandContext) error {
+			target, targets, channelID, err := parse(ctx)
+			if err != nil {
+				_, err = ctx.RespondText(err.Error())
+				return err
+			}
+			guildID := ctx.Message.GuildID
+
+			// Remove the override, keep an explicit one only if something else still disables the target
+			if err := store.DeleteOverride(guildID, channelID, target.name, target.isCategory); err != nil {
+				return err
+			}
+			overrides, err := store.GetOverrides(guildID)
+			if err != nil {
+				return err
+			}
+			o := &CommandOverride{guildID, channelID, target.name, target.isCategory, true}
+			if !overridesEnabled(overrides, channelID, targets) {
+				if err := store.SetOverride(o); err != nil {
+					return err
+				}
+			}
+			_, err = ctx.RespondText(o.String())
+			return err
+		},
+	)
+
+	list := NewMessageCommand(
+		"overrides",
+		"list the commands and categories enabled or disabled in this server",
+		[]string{"overrides"},
+		true,
+		[]*MessageCommandParam{},
+		[]*MessageCommand{},
+		func(ctx *MessageCommandContext) error {
+			overrides, err := store.GetOverrides(ctx.Message.GuildID)
+			if err != nil {
+				return err
+			}
+			if len(overrides) == 0 {
+				_, err = ctx.RespondText("Every command is enabled")
+				return err
+			}
+			lines := make([]string, len(overrides))
+			for i, o := range overrides {
+				lines[i] = o.String()
+			}
+			_, err = ctx.RespondText(strings.Join(lines, "\n"))
+			return err
+		},
+	)
+
+	cmds := []*MessageCommand{disable, enable, list}
+	for _, cmd := range cmds {
+		cmd.GuildOnly = true
+		cmd.Permissions = discordgo.PermissionManageServer
+		cmd.alwaysEnabled = true
+	}
+	return cmds
+}
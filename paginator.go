@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -38,6 +39,11 @@ func NewPaginator(s *discordgo.Session, channelID string) *Paginator {
 }
 
 func (p *Paginator) Deploy() error {
+	return p.DeployContext(context.Background())
+}
+
+// Same as Deploy, stop when ctx is done and return its error
+func (p *Paginator) DeployContext(ctx context.Context) error {
 	if len(p.Pages) == 0 {
 		return ErrPagesEmpty
 	}
@@ -72,7 +78,7 @@ func (p *Paginator) Deploy() error {
 	p.Widget.InitializeDefaultController()
 	p.Widget.View = page
 	p.Widget.View.Components = p.Widget.Controller
-	return p.Widget.Deploy()
+	return p.Widget.DeployContext(ctx)
 }
 
 func (p *Paginator) SetTimeout(duration time.Duration) {
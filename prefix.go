@@ -0,0 +1,201 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Resolve the prefixes the router listens to
+// guildID is empty for direct messages
+type PrefixResolver interface {
+	Prefixes(guildID string) []string
+}
+
+// Same prefixes for every server
+type StaticPrefixResolver []string
+
+func (p StaticPrefixResolver) Prefixes(guildID string) []string {
+	return p
+}
+
+// Storage for the custom prefixes of servers
+type PrefixStore interface {
+	// Return nil if the server has no custom prefix
+	GetPrefixes(guildID string) ([]string, error)
+	SetPrefixes(guildID string, prefixes []string) error
+	DeletePrefixes(guildID string) error
+}
+
+// In memory PrefixStore, prefixes are lost on restart
+type MemoryPrefixStore struct {
+	// To provide thread safe access endpoints
+	sync.RWMutex
+	Map 	map[string][]string
+}
+
+func NewMemoryPrefixStore() *MemoryPrefixStore {
+	return &MemoryPrefixStore{
+		Map: make(map[string][]string),
+	}
+}
+
+func (ps *MemoryPrefixStore) GetPrefixes(guildID string) ([]string, error) {
+	ps.RLock()
+	defer ps.RUnlock()
+
+	return ps.Map[guildID], nil
+}
+
+func (ps *MemoryPrefixStore) SetPrefixes(guildID string, prefixes []string) error {
+	ps.Lock()
+	defer ps.Unlock()
+
+	ps.Map[guildID] = append([]string{}, prefixes...)
+	return nil
+}
+
+func (ps *MemoryPrefixStore) DeletePrefixes(guildID string) error {
+	ps.Lock()
+	defer ps.Unlock()
+
+	delete(ps.Map, guildID)
+	return nil
+}
+
+// Use the custom prefixes of the server from Store
+// Fall back to Default for direct messages, servers without custom prefix or when the store fails
+type StorePrefixResolver struct {
+	Store 		PrefixStore
+	Default 	[]string
+}
+
+func NewStorePrefixResolver(store PrefixStore, defaults []string) *StorePrefixResolver {
+	return &StorePrefixResolver{
+		Store: 		store,
+		Default: 	defaults,
+	}
+}
+
+func (p *StorePrefixResolver) Prefixes(guildID string) []string {
+	if guildID == "" {
+		return p.Default
+	}
+	if prefixes, err := p.Store.GetPrefixes(guildID); err == nil && len(prefixes) != 0 {
+		return prefixes
+	}
+	return p.Default
+}
+
+// Limits of the custom prefixes of a server
+const (
+	maxPrefixes 		= 10
+	maxPrefixLength 	= 32
+)
+
+// Check the prefixes before storing them
+// Empty or whitespace only prefixes are rejected, they would match every message
+func validatePrefixes(prefixes []string) error {
+	if len(prefixes) > maxPrefixes {
+		return ErrTooManyPrefixes
+	}
+	for _, p := range prefixes {
+		if strings.TrimSpace(p) == "" {
+			return ErrEmptyPrefix
+		}
+		if len([]rune(p)) > maxPrefixLength {
+			return ErrPrefixTooLong
+		}
+	}
+	return nil
+}
+
+// Find the prefix used by the message
+// Mention prefix and direct messages without prefix are only checked when enabled on the router
+func (r *MessageCommandRouter) matchPrefix(s *discordgo.Session, m *discordgo.Message) (prefix string, exists bool) {
+	if _, prefix, exists = slicePrefixesString(m.Content, r.PrefixResolver.Prefixes(m.GuildID)); exists {
+		return prefix, true
+	}
+
+	if r.MentionPrefix && s.State != nil && s.State.User != nil {
+		for _, mention := range []string{"<@" + s.State.User.ID + ">", "<@!" + s.State.User.ID + ">"} {
+			if strings.HasPrefix(m.Content, mention) {
+				// The whitespaces after the mention are part of the prefix
+				rest := strings.TrimLeftFunc(m.Content[len(mention):], unicode.IsSpace)
+				return m.Content[:len(m.Content)-len(rest)], true
+			}
+		}
+	}
+
+	if r.DMWithoutPrefix && m.GuildID == "" {
+		return "", true
+	}
+
+	return "", false
+}
+
+// Built-in command to view and change the prefixes of a server
+// Changing prefixes requires Manage Server permission
+func NewPrefixCommand(resolver *StorePrefixResolver) *MessageCommand {
+	show := func(ctx *MessageCommandContext) error {
+		prefixes := resolver.Prefixes(ctx.Message.GuildID)
+		_, err := ctx.RespondText("Prefixes: `" + strings.Join(prefixes, "` `") + "`")
+		return err
+	}
+
+	set := NewMessageCommand(
+		"set",
+		"set the prefixes of this server",
+		[]string{"prefix set ! ?"},
+		true,
+		[]*MessageCommandParam{{"prefixes", MessageCommandParamTypeString, MessageCommandParamOptionList}},
+		[]*MessageCommand{},
+		func(ctx *MessageCommandContext) error {
+			tmp := ctx.ConvertedArgs["prefixes"].([]interface{})
+			prefixes := make([]string, len(tmp))
+			for i, v := range tmp {
+				prefixes[i] = v.(string)
+			}
+			if err := validatePrefixes(prefixes); err != nil {
+				return err
+			}
+			if err := resolver.Store.SetPrefixes(ctx.Message.GuildID, prefixes); err != nil {
+				return err
+			}
+			return show(ctx)
+		},
+	)
+
+	reset := NewMessageCommand(
+		"reset",
+		"restore the default prefixes of this server",
+		[]string{"prefix reset"},
+		true,
+		[]*MessageCommandParam{},
+		[]*MessageCommand{},
+		func(ctx *MessageCommandContext) error {
+			if err := resolver.Store.DeletePrefixes(ctx.Message.GuildID); err != nil {
+				return err
+			}
+			return show(ctx)
+		},
+	)
+
+	// Only server managers can change prefixes
+	for _, cmd := range []*MessageCommand{set, reset} {
+		cmd.GuildOnly = true
+		cmd.Permissions = discordgo.PermissionManageServer
+	}
+
+	return NewMessageCommand(
+		"prefix",
+		"show or change the prefixes of this server",
+		[]string{"prefix", "prefix set !", "prefix reset"},
+		true,
+		[]*MessageCommandParam{},
+		[]*MessageCommand{set, reset},
+		show,
+	)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidatePrefixes(t *testing.T) {
+	tests := []struct {
+		prefixes 	[]string
+		err 		error
+	}{
+		{[]string{"!", "t."}, nil},
+		{[]string{}, nil},
+		{[]string{""}, ErrEmptyPrefix},
+		{[]string{"!", " \n"}, ErrEmptyPrefix},
+		{[]string{strings.Repeat("a", maxPrefixLength)}, nil},
+		{[]string{strings.Repeat("é", maxPrefixLength+1)}, ErrPrefixTooLong},
+		{strings.Split(strings.Repeat("!", maxPrefixes+1), ""), ErrTooManyPrefixes},
+	}
+	for _, tt := range tests {
+		if err := validatePrefixes(tt.prefixes); !errors.Is(err, tt.err) {
+			t.Errorf("validatePrefixes(%q) = %v, want %v", tt.prefixes, err, tt.err)
+		}
+	}
+}
+
+func TestSlicePrefixesStringSkipsEmpty(t *testing.T) {
+	if _, _, exists := slicePrefixesString("hello", []string{""}); exists {
+		t.Error("empty prefix matched")
+	}
+	if _, prefix, exists := slicePrefixesString("!ping", []string{"", "!"}); !exists || prefix != "!" {
+		t.Errorf("got %q %v", prefix, exists)
+	}
+}
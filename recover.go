@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A panic recovered from a command or widget handler
+type RecoveredPanic struct {
+	// Value passed to panic
+	Value 		interface{}
+	Stack 		[]byte
+
+	// Full name of the command, empty for widget handlers
+	Command 	string
+
+	// Message that invoked the command, or the message of the widget
+	Message 	*discordgo.Message
+}
+
+func (p *RecoveredPanic) Error() string {
+	if p.Command == "" {
+		return fmt.Sprintf("panic: %v", p.Value)
+	}
+	return fmt.Sprintf("panic in command %s: %v", p.Command, p.Value)
+}
+
+// Log the panic and tell the user that the command failed
+func DefaultPanicHandler(ctx *MessageCommandContext, p *RecoveredPanic) {
+	log.Printf("%s\n%s", p.Error(), p.Stack)
+	ctx.RespondText("Something went wrong while running the command")
+}
+
+// Must be deferred directly, recover only works in the deferred function itself
+func (r *MessageCommandRouter) recoverPanic(ctx *MessageCommandContext) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	p := &RecoveredPanic{
+		Value: 		v,
+		Stack: 		debug.Stack(),
+		Message: 	ctx.Message,
+	}
+	if ctx.Command != nil {
+		p.Command = ctx.Command.FullName()
+	}
+
+	if r.OnPanic != nil {
+		r.OnPanic(ctx, p)
+		return
+	}
+	DefaultPanicHandler(ctx, p)
+}
+
+// Log the panic and tell the user who clicked that the interaction failed
+func DefaultWidgetPanicHandler(w *Widget, i *discordgo.Interaction, p *RecoveredPanic) {
+	log.Printf("%s\n%s", p.Error(), p.Stack)
+	w.Session.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "Something went wrong",
+			// Ephemeral
+			Flags: 1 << 6,
+		},
+	})
+}
+
+// Must be deferred directly, recover only works in the deferred function itself
+func (w *Widget) recoverPanic(i *discordgo.Interaction) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	p := &RecoveredPanic{
+		Value: 		v,
+		Stack: 		debug.Stack(),
+		Message: 	w.Message,
+	}
+
+	if w.OnPanic != nil {
+		w.OnPanic(w, i, p)
+		return
+	}
+	DefaultWidgetPanicHandler(w, i, p)
+}
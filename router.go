@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -9,30 +11,129 @@ import (
 
 func NewMessageCommandRouter(prefixes []string) *MessageCommandRouter {
 	return &MessageCommandRouter{
-		prefixes,
-		new(MessageCommandMap),
-		nil,
-		nil,
+		PrefixResolver: 	StaticPrefixResolver(prefixes),
+		CommandsMapping: 	new(MessageCommandMap),
+		Converters: 		NewConverterRegistry(DefaultConverters),
 	}
 }
 
+// Middleware wraps a handler and returns a new one
+// It can run code before and after calling the next handler or skip it entirely
 type MessageCommandMiddleware func(h MessageCommandHandler) MessageCommandHandler
 
+// Wrap handler with middlewares so that the first middleware is the outermost one
+func chainMiddlewares(h MessageCommandHandler, middlewares []MessageCommandMiddleware) MessageCommandHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
 type MessageCommandRouter struct {
 
-	// To store all prefixes of the bot
-	// Can change it to map[string]string or map[string][]string for server's custom prefix(es)
-	Prefixes 			[]string
+	// To get the prefixes of the server the message was sent in
+	PrefixResolver 		PrefixResolver
+
+	// Accept mentioning the bot (<@id> or <@!id>) as a prefix
+	MentionPrefix 		bool
+
+	// Accept commands without prefix in direct messages
+	DMWithoutPrefix 	bool
 
 	// Map command name to the command
 	// Key is the command name depends on IgnoreCase
 	CommandsMapping 	*MessageCommandMap
 
+	// IDs of the users owning the bot, they bypass cooldowns and pass OwnerOnly
+	Owners 				[]string
+
+	// Reply to unknown commands with the closest command names
+	SuggestCommands 	bool
+
+	// Max edit distance between an unknown command and a suggestion, 2 if 0
+	// Names starting with the unknown command are suggested regardless of distance
+	SuggestionDistance 	int
+
+	// Categories in the order they were added, see AddCategory
+	Categories 			[]*MessageCommandCategory
+
+	// Commands and categories enabled or disabled per server and channel, nil to ignore
+	Overrides 			CommandOverrideStore
+
+	// Parent of the context of every command, cancel it to stop running commands on shutdown
+	// Use context.Background() if nil
+	RootContext 		context.Context
+
+	// Converters of param types, types without converter here use DefaultConverters
+	Converters 			*ConverterRegistry
+
+	// Run handlers on a bounded pool of workers, nil to run each one in a new goroutine
+	// A handler holds its worker until it returns, long waits should use MessageCommandContext.Detach
+	// Never share it with an EventWaiter, see EventWaiter.UseExecutor
+	Executor 			*Executor
+
+	// Global middlewares, wrapped around every command
+	// Executed in the order they were added, before the command's own middlewares
+	Middlewares 		[]MessageCommandMiddleware
+
 	// Function invoked before the command
 	Before func(*MessageCommandContext)
 
 	// Function invoked after the command
 	After func()
+
+	// Function invoked when the command cannot run or its handler returns an error
+	// Command of ctx is nil if the error happens before the command is found
+	// Use DefaultErrorHandler if nil
+	OnError func(ctx *MessageCommandContext, err error)
+
+	// Function invoked when a command panics, the bot keeps running
+	// Use DefaultPanicHandler if nil
+	OnPanic func(ctx *MessageCommandContext, p *RecoveredPanic)
+
+	// Re-run commands when the invoking message is edited, see EditHandler
+	RerunOnEdit 		bool
+
+	// How long after being sent an edited message re-runs its command, 2 minutes if 0
+	EditWindow 			time.Duration
+
+	// Delete the responses of a command when the invoking message is deleted
+	// See DeleteHandler and DeleteBulkHandler
+	DeleteResponses 	bool
+
+	// How long responses are tracked, 10 minutes if 0
+	// Extended to EditWindow if RerunOnEdit is enabled
+	TrackingTTL 		time.Duration
+
+	// Max number of tracked invoking messages, the oldest ones are forgotten first, 1000 if 0
+	MaxTrackedInvocations int
+
+	// Uses of commands with a cooldown
+	cooldowns 			cooldownMapping
+
+	// Running uses of commands with a max concurrency
+	concurrency 		concurrencyMapping
+
+	// Responses to invoking messages
+	invocations 		invocationTracker
+
+	// To provide thread safe access to Categories
+	categoryLock 		sync.RWMutex
+}
+
+// Add global middlewares to the router
+func (r *MessageCommandRouter) Use(middlewares ...MessageCommandMiddleware) {
+	r.Middlewares = append(r.Middlewares, middlewares...)
+}
+
+// Check if the user is one of the owners of the bot
+func (r *MessageCommandRouter) IsOwner(userID string) bool {
+	for _, owner := range r.Owners {
+		if owner == userID {
+			return true
+		}
+	}
+	return false
 }
 
 func (r *MessageCommandRouter) GetCommand(name string) *MessageCommand {
@@ -49,79 +150,296 @@ func (r *MessageCommandRouter) GetCommand(name string) *MessageCommand {
 	return nil
 }
 
-func (r *MessageCommandRouter) AddCommand(cmd *MessageCommand) {
-	r.CommandsMapping.Set(cmd)
+// Add the command and put it in its category
+// Return a CommandExistsError if its name or an alias is already used
+// Return an InvalidCommandError if it or one of its subcommands is misconfigured
+func (r *MessageCommandRouter) AddCommand(cmd *MessageCommand) error {
+	if err := cmd.validate(); err != nil {
+		return err
+	}
+	if err := r.CommandsMapping.Set(cmd); err != nil {
+		return err
+	}
+	r.categorize(cmd)
+	return nil
+}
+
+// Remove the command with its aliases and take it out of its category
+// Return false if the command was not added
+func (r *MessageCommandRouter) RemoveCommand(cmd *MessageCommand) bool {
+	if !r.CommandsMapping.RemoveCommand(cmd) {
+		return false
+	}
+	r.uncategorize(cmd)
+	return true
+}
+
+// Get every command added to the router once, sorted by name
+func (r *MessageCommandRouter) Commands() []*MessageCommand {
+	return r.CommandsMapping.Commands()
+}
+
+// Replace every command of the router at once, like when reloading them
+// Categories are kept but only contain the new commands
+// Return a CommandExistsError and keep the current commands if two of them collide
+// Return an InvalidCommandError and keep the current commands if one of them is misconfigured
+func (r *MessageCommandRouter) ReplaceCommands(cmds []*MessageCommand) error {
+	for _, cmd := range cmds {
+		if err := cmd.validate(); err != nil {
+			return err
+		}
+	}
+
+	r.categoryLock.Lock()
+	defer r.categoryLock.Unlock()
+
+	if err := r.CommandsMapping.ReplaceAll(cmds); err != nil {
+		return err
+	}
+	for _, c := range r.Categories {
+		c.Item = []*MessageCommand{}
+	}
+	for _, cmd := range cmds {
+		r.addToCategory(cmd)
+	}
+	return nil
+}
+
+// Run the handlers passed to Detach in order, then free what the command holds
+func (r *MessageCommandRouter) runDetached(ctx *MessageCommandContext, finish func()) {
+	defer r.recoverPanic(ctx)
+	defer finish()
+
+	// Detached handlers may detach more handlers
+	for len(ctx.detached) != 0 {
+		h := ctx.detached[0]
+		ctx.detached = ctx.detached[1:]
+		if err := h(ctx); err != nil {
+			r.handleError(ctx, err)
+		}
+	}
 }
 
 func (r *MessageCommandRouter) Handler() func(s *discordgo.Session, m *discordgo.MessageCreate) {
 	return func(s *discordgo.Session, m *discordgo.MessageCreate) {
-		if m.Author.Bot {
-			return
+		r.dispatch(s, m.Message)
+	}
+}
+
+// Find and run the command invoked by the message
+func (r *MessageCommandRouter) dispatch(s *discordgo.Session, m *discordgo.Message) {
+	if m.Author.Bot {
+		return
+	}
+
+	// The bot is shutting down
+	if r.rootContext().Err() != nil {
+		return
+	}
+
+	// Get prefixes
+	prefix, exists := r.matchPrefix(s, m)
+
+	if !exists {
+		return
+	}
+
+	// Errors before the command is found are reported with a partial context
+	ctx := &MessageCommandContext{
+		Session: 		s,
+		Message: 		m,
+		Router: 		r,
+		permissions: 	&permissionCache{},
+	}
+	defer r.recoverPanic(ctx)
+
+	if r.tracking() {
+		ctx.invocation = r.trackInvocation(m)
+	}
+
+	// Responses of the previous run not reused are deleted once this run is over
+	// The run deletes them itself if the command is handed to it
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			r.deletePending(ctx)
 		}
+	}()
 
-		// Get prefixes
-		_, prefix, exists := slicePrefixesString(m.Content, r.Prefixes)
+	commandName, arguments, err := parseContent(m.Content, prefix)
+	if err != nil {
+		r.handleError(ctx, err)
+		return
+	}
+	if commandName == "" {
+		return
+	}
+	ctx.Trigger = commandName
 
-		if !exists {
-			return
+	var cmd *MessageCommand
+	if cmd = r.GetCommand(commandName); cmd == nil {
+		notFound := &CommandNotFoundError{Name: commandName}
+		if r.SuggestCommands {
+			notFound.Suggestions = r.suggestCommands(ctx, commandName)
 		}
+		r.handleError(ctx, notFound)
+		return
+	}
 
-		commandName, arguments := parseContent(m.Content, prefix)
+	// Descend into subcommands, the deepest matched one handles the rest
+	cmd, arguments = cmd.resolveSubCommand(arguments)
+	ctx.Command = cmd
 
-		var cmd *MessageCommand
-		if cmd = r.GetCommand(commandName); cmd == nil {
-			return
+	// Reject the command if it is disabled in the server or the channel
+	if err := r.checkOverrides(cmd, m); err != nil {
+		r.handleError(ctx, err)
+		return
+	}
+
+	// Run checks of the command and its parents
+	if err := r.CanRun(ctx, cmd); err != nil {
+		r.handleError(ctx, err)
+		return
+	}
+
+	// A command without handler only groups its subcommands, show them instead
+	if cmd.Handler == nil {
+		ctx.Respond(&discordgo.MessageSend{
+			Embed: r.CommandEmbed(cmd),
+		})
+		return
+	}
+
+	// Separate flags from positional arguments
+	arguments, flags, err := cmd.splitFlags(arguments)
+	if err != nil {
+		r.handleError(ctx, err)
+		return
+	}
+	ctx.RawArgs, ctx.RawFlags = arguments, flags
+
+	// Validate arguments
+	if err := cmd.checkArguments(arguments); err != nil {
+		r.handleError(ctx, err)
+		return
+	}
+
+	// Get converted arguments, flags are put along with params
+	converted, err := cmd.ConvertArguments(ctx, arguments)
+	if err == nil {
+		var convertedFlags map[string]interface{}
+		if convertedFlags, err = cmd.ConvertFlags(ctx, flags); err == nil {
+			for name, value := range convertedFlags {
+				converted[name] = value
+			}
 		}
+	}
+	if err != nil {
+		r.handleError(ctx, err)
+		return
+	}
+	ctx.ConvertedArgs = converted
+
+	// Global middlewares wrap the middlewares of the command and its parents
+	chain := cmd.middlewareChain()
+	middlewares := make([]MessageCommandMiddleware, 0, len(r.Middlewares) + len(chain))
+	middlewares = append(middlewares, r.Middlewares...)
+	middlewares = append(middlewares, chain...)
+	handler := chainMiddlewares(cmd.Handler, middlewares)
+
+	// Reject uses beyond the max concurrency of the command, the slot is taken when the command runs
+	use, err := r.reserveConcurrency(ctx, cmd)
+	if err != nil {
+		r.handleError(ctx, err)
+		return
+	}
+
+	// Reject the command if it is cooling down, uses are only counted for commands about to run
+	refund, err := r.checkCooldown(cmd, m)
+	if err != nil {
+		use.release()
+		r.handleError(ctx, err)
+		return
+	}
+
+	run := func ()  {
+		defer r.recoverPanic(ctx)
 
-		// Validate arguments
-		if !cmd.ValidateArguments(arguments) {
+		// Wait for a slot here so the event handler is never blocked by it
+		if err := r.waitConcurrency(cmd, use); err != nil {
+			refund()
+			use.release()
+			r.handleError(ctx, err)
+			r.deletePending(ctx)
 			return
 		}
 
-		// Get converted arguments
-		var conv map[string]interface{}
-		if converted, err := cmd.ConvertArguments(s, m.Message, arguments); err != nil {
-			msg, er := s.ChannelMessageSendReply(
-				m.ChannelID, 
-				"Not valid arguments. Use help <command> for more info", 
-				&discordgo.MessageReference{
-					ChannelID: m.ChannelID,
-					MessageID: m.ID,
-					GuildID: m.GuildID,
-			})
-			if er != nil {
-				return
-			}
-			go func() {
-				time.Sleep(time.Second * 5)
-				s.ChannelMessageDelete(msg.ChannelID, msg.ID)
-			}()
-			return
+		// Cancelled with the root context, or when the command times out
+		// Created here so the time spent waiting for a slot or a worker does not count against the timeout
+		var cancel context.CancelFunc
+		if timeout := cmd.timeout(); timeout != 0 {
+			ctx.context, cancel = context.WithTimeout(r.rootContext(), timeout)
 		} else {
-			conv = converted
+			ctx.context, cancel = context.WithCancel(r.rootContext())
+		}
+		finish := func() {
+			r.deletePending(ctx)
+			use.release()
+			cancel()
+		}
+
+		detached := false
+		defer func() {
+			if !detached {
+				finish()
+			}
+		}()
+
+		if r.Before != nil {
+			r.Before(ctx)
+		}
+		if err := handler(ctx); err != nil {
+			r.handleError(ctx, err)
 		}
 
-		ctx := &MessageCommandContext{
-			s,
-			m.Message,
-			r,
-			commandName,
-			arguments,
-			conv,
-			cmd,
+		if r.After != nil {
+			r.After()
 		}
 
-		handler := cmd.Handler
+		// Free the worker, the detached handlers finish the command
+		if len(ctx.detached) != 0 {
+			detached = true
+			go r.runDetached(ctx, finish)
+		}
+	}
 
-		go func ()  {
-			if r.Before != nil {
-				r.Before(ctx)
-			}
-			handler(ctx)
+	if r.Executor == nil {
+		handedOff = true
+		go run()
+		return
+	}
+	// Do not block the event handler for long if the executor waits for room in its queue
+	if err := r.Executor.submitWithin(r.rootContext(), submitTimeout, run); err != nil {
+		refund()
+		use.release()
+		r.handleError(ctx, err)
+		return
+	}
+	handedOff = true
+}
 
-			if r.After != nil {
-				r.After()
-			}
-		}()
+func (r *MessageCommandRouter) rootContext() context.Context {
+	if r.RootContext == nil {
+		return context.Background()
 	}
-}
\ No newline at end of file
+	return r.RootContext
+}
+
+// Pass the error to OnError, or DefaultErrorHandler if not set
+func (r *MessageCommandRouter) handleError(ctx *MessageCommandContext, err error) {
+	if r.OnError != nil {
+		r.OnError(ctx, err)
+		return
+	}
+	DefaultErrorHandler(ctx, err)
+}
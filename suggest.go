@@ -0,0 +1,120 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Max number of suggestions for an unknown command
+const maxSuggestions = 3
+
+// Used when SuggestionDistance of the router is 0
+const defaultSuggestionDistance = 2
+
+// Edit distance between a and b, swapping two adjacent characters counts as one edit
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// Keep the last three rows, a transposition looks two rows back
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = minInt(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(first int, rest ...int) int {
+	for _, v := range rest {
+		if v < first {
+			first = v
+		}
+	}
+	return first
+}
+
+// Find the names and aliases closest to name among the commands the user can use
+// A name matches if it is within the edit distance threshold or starts with name
+func (r *MessageCommandRouter) suggestCommands(ctx *MessageCommandContext, name string) []string {
+	threshold := r.SuggestionDistance
+	if threshold == 0 {
+		threshold = defaultSuggestionDistance
+	}
+	name = strings.ToLower(name)
+
+	type candidate struct {
+		key 	string
+		cmd 	*MessageCommand
+		prefix 	bool
+		dist 	int
+	}
+
+	// Prefix matches first, then closest distance, then alphabetical
+	less := func(a, b candidate) bool {
+		if a.prefix != b.prefix {
+			return a.prefix
+		}
+		if a.dist != b.dist {
+			return a.dist < b.dist
+		}
+		return a.key < b.key
+	}
+
+	// Keep the best key of every command
+	best := make(map[*MessageCommand]candidate)
+	for _, cmd := range r.Commands() {
+		for _, key := range cmd.keys() {
+			c := candidate{
+				key: 	key,
+				cmd: 	cmd,
+				prefix: len(name) >= 2 && strings.HasPrefix(strings.ToLower(key), name),
+				dist: 	editDistance(name, strings.ToLower(key)),
+			}
+			if !c.prefix && c.dist > threshold {
+				continue
+			}
+			if b, ok := best[cmd]; !ok || less(c, b) {
+				best[cmd] = c
+			}
+		}
+	}
+
+	candidates := make([]candidate, 0, len(best))
+	for _, c := range best {
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return less(candidates[i], candidates[j])
+	})
+
+	suggestions := []string{}
+	for _, c := range candidates {
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+		// Do not reveal commands the user cannot use or disabled in the channel
+		if !r.canUse(ctx, c.cmd) {
+			continue
+		}
+		suggestions = append(suggestions, c.key)
+	}
+
+	return suggestions
+}
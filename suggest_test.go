@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b 	string
+		want 	int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"help", "help", 0},
+		{"kitten", "sitting", 3},
+		{"hepl", "help", 1},
+		{"randrnage", "randrange", 1},
+		{"ab", "ba", 1},
+		{"abc", "ca", 3},
+		{"prefx", "prefix", 1},
+		{"héllo", "hello", 1},
+		{"日本語", "日本", 1},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := editDistance(tt.b, tt.a); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestCommands(t *testing.T) {
+	r := NewMessageCommandRouter(nil)
+	for _, name := range []string{"random", "randrange", "help", "prefix", "ban"} {
+		if err := r.AddCommand(NewMessageCommand(name, "", nil, true, nil, nil, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	owner := NewMessageCommand("shutdown", "", nil, true, nil, nil, nil)
+	owner.OwnerOnly = true
+	if err := r.AddCommand(owner); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &MessageCommandContext{
+		Router: 	r,
+		Message: 	&discordgo.Message{Author: &discordgo.User{ID: "1"}},
+	}
+
+	tests := []struct {
+		name 	string
+		want 	[]string
+	}{
+		{"hepl", []string{"help"}},
+		{"randrnage", []string{"randrange"}},
+		{"ra", []string{"random", "randrange", "ban"}},
+		{"RANDOM", []string{"random"}},
+		{"zzzzzz", []string{}},
+		{"shutdwon", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := r.suggestCommands(ctx, tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("suggestCommands(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestCommandsHidesDisabled(t *testing.T) {
+	r := NewMessageCommandRouter(nil)
+	r.Overrides = NewMemoryOverrideStore()
+	for _, name := range []string{"random", "randrange"} {
+		if err := r.AddCommand(NewMessageCommand(name, "", nil, true, nil, nil, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Overrides.SetOverride(&CommandOverride{GuildID: "1", ChannelID: "10", Target: "random"})
+
+	ctx := &MessageCommandContext{
+		Router: 	r,
+		Message: 	&discordgo.Message{GuildID: "1", ChannelID: "10", Author: &discordgo.User{ID: "1"}},
+	}
+	if got := r.suggestCommands(ctx, "randm"); !reflect.DeepEqual(got, []string{}) {
+		t.Errorf("suggestCommands in the disabled channel = %q, want none", got)
+	}
+
+	ctx.Message.ChannelID = "20"
+	if got := r.suggestCommands(ctx, "randm"); !reflect.DeepEqual(got, []string{"random"}) {
+		t.Errorf("suggestCommands in another channel = %q, want random", got)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Whether a code fence starts at rs[i]
+func isCodeFence(rs []rune, i int) bool {
+	return i+2 < len(rs) && rs[i] == '`' && rs[i+1] == '`' && rs[i+2] == '`'
+}
+
+// Index of the first code fence of rs starting at from or after, -1 if there is none
+func indexCodeFence(rs []rune, from int) int {
+	for i := from; i+2 < len(rs); i++ {
+		if isCodeFence(rs, i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Split the content into arguments
+//    Any whitespace separates arguments, consecutive whitespaces do not produce empty arguments
+//    "double" and 'single' quotes group whitespaces into one argument, "" is an empty argument
+//    Double quotes can start anywhere (--name="a b"), single quotes only at the start of an argument (don't)
+//    Backslash escapes the next character, inside quotes it only escapes the quote and itself
+//    Fenced code blocks are kept as one argument as is, fences included
+func tokenize(s string) ([]string, error) {
+	var (
+		tokens 	[]string
+		current strings.Builder
+		// Whether there is a token being built, to keep empty quoted arguments
+		inToken bool
+	)
+
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+
+		switch {
+		case unicode.IsSpace(c):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+
+		case c == '\\':
+			inToken = true
+			if i+1 < len(rs) {
+				i++
+			}
+			current.WriteRune(rs[i])
+
+		case isCodeFence(rs, i):
+			end := indexCodeFence(rs, i+3)
+			if end == -1 {
+				return nil, ErrUnclosedCodeBlock
+			}
+			current.WriteString(string(rs[i:end+3]))
+			inToken = true
+			i = end + 2
+
+		case c == '"' || (c == '\'' && !inToken):
+			inToken = true
+			closed := false
+			for i++; i < len(rs); i++ {
+				if rs[i] == c {
+					closed = true
+					break
+				}
+				if rs[i] == '\\' && i+1 < len(rs) && (rs[i+1] == c || rs[i+1] == '\\') {
+					i++
+				}
+				current.WriteRune(rs[i])
+			}
+			if !closed {
+				return nil, ErrUnclosedQuote
+			}
+
+		default:
+			inToken = true
+			current.WriteRune(c)
+		}
+	}
+
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,22 @@
+//go:build go1.18
+// +build go1.18
+
+package main
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzTokenize(f *testing.F) {
+	for _, seed := range []string{"", `""`, `a "b c" 'd'`, `a\ b`, "```go\nx```", "é```ü", `"a \"b\"`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		// Discord only sends valid UTF-8 content
+		if !utf8.ValidString(s) {
+			t.Skip()
+		}
+		checkTokenize(t, s)
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name 	string
+		in 		string
+		want 	[]string
+	}{
+		{"empty", "", nil},
+		{"only whitespaces", " \t\n ", nil},
+		{"words", "a b c", []string{"a", "b", "c"}},
+		{"runs of whitespaces", "  a \t\t b\n\nc  ", []string{"a", "b", "c"}},
+		{"newlines", "a\nb\r\nc", []string{"a", "b", "c"}},
+		{"double quotes", `say "hello world"`, []string{"say", "hello world"}},
+		{"single quotes", `say 'hello world'`, []string{"say", "hello world"}},
+		{"empty quotes", `a "" b`, []string{"a", "", "b"}},
+		{"empty single quotes", `a '' b`, []string{"a", "", "b"}},
+		{"only empty quotes", `""`, []string{""}},
+		{"double quotes inside token", `--name="a b"`, []string{"--name=a b"}},
+		{"apostrophe inside token", `don't stop`, []string{"don't", "stop"}},
+		{"adjacent quotes", `"a""b"`, []string{"ab"}},
+		{"single quote after token start", `"a"'b'`, []string{"a'b'"}},
+		{"single quote inside double", `"it's"`, []string{"it's"}},
+		{"escaped space", `a\ b`, []string{"a b"}},
+		{"escaped quote", `\"a b\"`, []string{`"a`, `b"`}},
+		{"escaped quote in quotes", `"a \"b\" c"`, []string{`a "b" c`}},
+		{"escaped backslash in quotes", `"a\\b"`, []string{`a\b`}},
+		{"other escape in quotes", `"a\nb"`, []string{`a\nb`}},
+		{"trailing backslash", `a\`, []string{`a\`}},
+		{"code block", "run ```go\nfmt.Println(\"a b\")\n```", []string{"run", "```go\nfmt.Println(\"a b\")\n```"}},
+		{"code block glued to text", "x```a b```y z", []string{"x```a b```y", "z"}},
+		{"non-ASCII before code block", "héllo ```a```", []string{"héllo", "```a```"}},
+		{"non-ASCII inside code block", "```日本 語``` ok", []string{"```日本 語```", "ok"}},
+		{"non-ASCII around code block", "é```ü ß```ö", []string{"é```ü ß```ö"}},
+		{"non-ASCII words", "héllo wörld 日本", []string{"héllo", "wörld", "日本"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenize(tt.in)
+			if err != nil {
+				t.Fatalf("tokenize(%q) error: %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tests := []struct {
+		in 		string
+		err 	error
+	}{
+		{`say "hello`, ErrUnclosedQuote},
+		{`say 'hello`, ErrUnclosedQuote},
+		{`"`, ErrUnclosedQuote},
+		{`"a \"`, ErrUnclosedQuote},
+		{"```go\nfmt.Println()", ErrUnclosedCodeBlock},
+		{"é```ü", ErrUnclosedCodeBlock},
+		{"``````", nil},
+	}
+
+	for _, tt := range tests {
+		if _, err := tokenize(tt.in); err != tt.err {
+			t.Errorf("tokenize(%q) error = %v, want %v", tt.in, err, tt.err)
+		}
+	}
+}
+
+// Random content made of the characters the tokenizer treats specially
+func randomContent(rnd *rand.Rand) string {
+	alphabet := []string{"a", "b", "é", "日", " ", "  ", "\t", "\n", `"`, "'", `\`, "`", "```", "-"}
+	var b strings.Builder
+	for n := rnd.Intn(20); n > 0; n-- {
+		b.WriteString(alphabet[rnd.Intn(len(alphabet))])
+	}
+	return b.String()
+}
+
+// Check the properties of tokenize that hold for any content
+func checkTokenize(t *testing.T, s string) {
+	defer func() {
+		if v := recover(); v != nil {
+			t.Fatalf("tokenize(%q) panicked: %v", s, v)
+		}
+	}()
+
+	tokens, err := tokenize(s)
+	if err != nil {
+		return
+	}
+
+	// Only quotes produce empty tokens
+	if !strings.ContainsAny(s, `"'`) {
+		for _, token := range tokens {
+			if token == "" {
+				t.Fatalf("tokenize(%q) = %q has an empty token", s, tokens)
+			}
+		}
+	}
+
+	// Without special characters it is the same as splitting on whitespaces
+	if !strings.ContainsAny(s, "\"'\\`") && !reflect.DeepEqual(tokens, nilIfEmpty(strings.Fields(s))) {
+		t.Fatalf("tokenize(%q) = %q, want %q", s, tokens, strings.Fields(s))
+	}
+}
+
+func nilIfEmpty(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+func TestTokenizeProperties(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		checkTokenize(t, randomContent(rnd))
+	}
+}
+
+// Messages of the max length Discord allows, tokenizing them must stay linear
+func longContents() []string {
+	return []string{
+		strings.Repeat("a ", 2000),
+		strings.Repeat("é", 4000),
+		"```" + strings.Repeat("ü", 3994) + "```",
+		strings.Repeat("`", 4000),
+	}
+}
+
+func TestTokenizeLongContent(t *testing.T) {
+	tokens, err := tokenize(longContents()[2])
+	if err != nil || len(tokens) != 1 || len([]rune(tokens[0])) != 4000 {
+		t.Errorf("tokenize of a long code block = %d tokens, %v", len(tokens), err)
+	}
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	contents := longContents()
+	for i := 0; i < b.N; i++ {
+		for _, s := range contents {
+			tokenize(s)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Used when EditWindow of the router is 0
+const defaultEditWindow = 2 * time.Minute
+
+// Used when TrackingTTL of the router is 0
+const defaultTrackingTTL = 10 * time.Minute
+
+// Used when MaxTrackedInvocations of the router is 0
+const defaultMaxTrackedInvocations = 1000
+
+// Responses of the bot to a message that invoked a command
+type invocation struct {
+	sync.Mutex
+
+	created 	time.Time
+
+	// Content and edit time of the invoking message when this run started
+	// Compared with updates of the message to ignore the ones not editing it
+	content 	string
+	edited 		time.Time
+
+	// Messages sent or edited by the context, in order
+	responses 	[]*discordgo.Message
+
+	// Responses of the previous run to edit instead of sending new messages
+	pending 	[]*discordgo.Message
+
+	// The invoking message was deleted, later responses are deleted right away
+	removed 	bool
+}
+
+// Pop the next response of the previous run
+func (inv *invocation) nextPending() *discordgo.Message {
+	inv.Lock()
+	defer inv.Unlock()
+
+	if len(inv.pending) == 0 {
+		return nil
+	}
+	msg := inv.pending[0]
+	inv.pending = inv.pending[1:]
+	return msg
+}
+
+// Take every response of the previous run not reused yet
+func (inv *invocation) takePending() []*discordgo.Message {
+	inv.Lock()
+	defer inv.Unlock()
+
+	msgs := inv.pending
+	inv.pending = nil
+	return msgs
+}
+
+// Return false if the invoking message is already deleted
+func (inv *invocation) addResponse(msg *discordgo.Message) bool {
+	inv.Lock()
+	defer inv.Unlock()
+
+	if inv.removed {
+		return false
+	}
+	inv.responses = append(inv.responses, msg)
+	return true
+}
+
+// Mark the invocation removed and return every message it still owns
+func (inv *invocation) remove() []*discordgo.Message {
+	inv.Lock()
+	defer inv.Unlock()
+
+	inv.removed = true
+	msgs := append(append([]*discordgo.Message{}, inv.responses...), inv.pending...)
+	inv.responses, inv.pending = nil, nil
+	return msgs
+}
+
+// Map the ID of the invoking message to its invocation
+// Invocations expire after a TTL and the oldest ones are evicted above a max size
+type invocationTracker struct {
+	sync.Mutex
+	invocations map[string]*invocation
+
+	// IDs of invoking messages from oldest to newest, may contain removed IDs
+	order 		[]string
+	lastSweep 	time.Time
+}
+
+// Start tracking a new run of the message
+// Responses of the previous run of the same message become pending
+func (t *invocationTracker) start(m *discordgo.Message, now time.Time, ttl time.Duration, limit int) *invocation {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.invocations == nil {
+		t.invocations = make(map[string]*invocation)
+	}
+	t.sweep(now, ttl)
+
+	inv := &invocation{
+		created: 	now,
+		content: 	m.Content,
+	}
+	if m.EditedTimestamp != nil {
+		inv.edited = *m.EditedTimestamp
+	}
+	if prev, ok := t.invocations[m.ID]; ok {
+		prev.Lock()
+		// Responses not reused by the previous run are still there
+		inv.pending = append(append(inv.pending, prev.responses...), prev.pending...)
+		inv.created = prev.created
+		prev.Unlock()
+	} else {
+		t.order = append(t.order, m.ID)
+	}
+	t.invocations[m.ID] = inv
+
+	// Evict the oldest invocations
+	for len(t.invocations) > limit && len(t.order) != 0 {
+		delete(t.invocations, t.order[0])
+		t.order = t.order[1:]
+	}
+
+	return inv
+}
+
+// Stop tracking the message and return its invocation, nil if not tracked
+func (t *invocationTracker) take(messageID string) *invocation {
+	t.Lock()
+	defer t.Unlock()
+
+	inv, ok := t.invocations[messageID]
+	if !ok {
+		return nil
+	}
+	delete(t.invocations, messageID)
+	return inv
+}
+
+// Check if the content or the edit time of the message differ from the ones of its last run
+// Messages not tracked are considered changed
+func (t *invocationTracker) changed(m *discordgo.Message) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	inv, ok := t.invocations[m.ID]
+	if !ok {
+		return true
+	}
+	var edited time.Time
+	if m.EditedTimestamp != nil {
+		edited = *m.EditedTimestamp
+	}
+	return inv.content != m.Content && !inv.edited.Equal(edited)
+}
+
+// Remove invocations older than ttl at most once a minute
+func (t *invocationTracker) sweep(now time.Time, ttl time.Duration) {
+	if now.Sub(t.lastSweep) < time.Minute {
+		return
+	}
+	t.lastSweep = now
+	order := make([]string, 0, len(t.invocations))
+	for _, id := range t.order {
+		inv, ok := t.invocations[id]
+		if !ok {
+			continue
+		}
+		if now.Sub(inv.created) > ttl {
+			delete(t.invocations, id)
+			continue
+		}
+		order = append(order, id)
+	}
+	t.order = order
+}
+
+func (r *MessageCommandRouter) editWindow() time.Duration {
+	if r.EditWindow == 0 {
+		return defaultEditWindow
+	}
+	return r.EditWindow
+}
+
+// Whether contexts record their responses
+func (r *MessageCommandRouter) tracking() bool {
+	return r.RerunOnEdit || r.DeleteResponses
+}
+
+// Start tracking the responses to the message
+// Invocations live long enough to be re-run within EditWindow
+func (r *MessageCommandRouter) trackInvocation(m *discordgo.Message) *invocation {
+	ttl := r.TrackingTTL
+	if ttl == 0 {
+		ttl = defaultTrackingTTL
+	}
+	if r.RerunOnEdit && ttl < r.editWindow() {
+		ttl = r.editWindow()
+	}
+
+	limit := r.MaxTrackedInvocations
+	if limit == 0 {
+		limit = defaultMaxTrackedInvocations
+	}
+
+	return r.invocations.start(m, time.Now(), ttl, limit)
+}
+
+// Delete the responses of the previous run that the run of ctx did not reuse
+func (r *MessageCommandRouter) deletePending(ctx *MessageCommandContext) {
+	if ctx.invocation == nil {
+		return
+	}
+	deleteMessages(ctx.Session, ctx.Message.GuildID, ctx.Message.ChannelID, ctx.invocation.takePending())
+}
+
+// Handler deleting the responses of a command when the invoking message is deleted
+// Only works if DeleteResponses is enabled
+func (r *MessageCommandRouter) DeleteHandler() func(s *discordgo.Session, m *discordgo.MessageDelete) {
+	return func(s *discordgo.Session, m *discordgo.MessageDelete) {
+		if !r.DeleteResponses || m.Message == nil {
+			return
+		}
+		if inv := r.invocations.take(m.ID); inv != nil {
+			deleteMessages(s, m.GuildID, m.ChannelID, inv.remove())
+		}
+	}
+}
+
+// Same as DeleteHandler for messages deleted in bulk
+func (r *MessageCommandRouter) DeleteBulkHandler() func(s *discordgo.Session, m *discordgo.MessageDeleteBulk) {
+	return func(s *discordgo.Session, m *discordgo.MessageDeleteBulk) {
+		if !r.DeleteResponses {
+			return
+		}
+		msgs := []*discordgo.Message{}
+		for _, id := range m.Messages {
+			if inv := r.invocations.take(id); inv != nil {
+				msgs = append(msgs, inv.remove()...)
+			}
+		}
+		deleteMessages(s, m.GuildID, m.ChannelID, msgs)
+	}
+}
+
+// Max number of messages deleted by one bulk delete request
+const maxBulkDelete = 100
+
+// Delete messages of a channel, in bulk if there are several in a server
+// Bulk deletes only work in servers, direct messages are deleted one by one
+func deleteMessages(s *discordgo.Session, guildID string, channelID string, msgs []*discordgo.Message) {
+	ids := make([]string, len(msgs))
+	for i, msg := range msgs {
+		ids[i] = msg.ID
+	}
+
+	for _, chunk := range bulkDeleteChunks(guildID, ids) {
+		if len(chunk) == 1 {
+			s.ChannelMessageDelete(channelID, chunk[0])
+			continue
+		}
+		// Messages older than two weeks cannot be deleted in bulk
+		if err := s.ChannelMessagesBulkDelete(channelID, chunk); err != nil {
+			for _, id := range chunk {
+				s.ChannelMessageDelete(channelID, id)
+			}
+		}
+	}
+}
+
+// Split ids into the groups deleted by one request each
+func bulkDeleteChunks(guildID string, ids []string) [][]string {
+	size := maxBulkDelete
+	if guildID == "" {
+		size = 1
+	}
+	chunks := [][]string{}
+	for len(ids) > size {
+		chunks = append(chunks, ids[:size])
+		ids = ids[size:]
+	}
+	if len(ids) != 0 {
+		chunks = append(chunks, ids)
+	}
+	return chunks
+}
+
+// Handler re-running commands when the invoking message is edited within EditWindow
+// The first responses of the new run edit the responses of the previous run
+// Only works if RerunOnEdit is enabled
+func (r *MessageCommandRouter) EditHandler() func(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	return func(s *discordgo.Session, m *discordgo.MessageUpdate) {
+		if !r.RerunOnEdit || m.Message == nil || m.Author == nil || m.Author.Bot {
+			return
+		}
+
+		// Updates not made by the author, like pins or embeds being resolved, keep the edit time
+		// The state only has the previous content if it caches messages, so the tracker keeps its own
+		if m.EditedTimestamp == nil || !r.invocations.changed(m.Message) {
+			return
+		}
+		if m.BeforeUpdate != nil && m.BeforeUpdate.Content == m.Content {
+			return
+		}
+
+		created, err := discordgo.SnowflakeTimestamp(m.ID)
+		if err != nil || time.Since(created) > r.editWindow() {
+			return
+		}
+
+		// The message is no longer a command, its previous responses are out of date
+		if _, ok := r.matchPrefix(s, m.Message); !ok {
+			if inv := r.invocations.take(m.ID); inv != nil {
+				deleteMessages(s, m.GuildID, m.ChannelID, inv.remove())
+			}
+			return
+		}
+
+		r.dispatch(s, m.Message)
+	}
+}
+
+// Send d, or edit the next pending response with it when re-running after an edit
+func (ctx *MessageCommandContext) send(d *discordgo.MessageSend) (*discordgo.Message, error) {
+	inv := ctx.invocation
+	if inv == nil {
+		return ctx.Session.ChannelMessageSendComplex(ctx.Message.ChannelID, d)
+	}
+
+	embeds := d.Embeds
+	if d.Embed != nil {
+		embeds = append([]*discordgo.MessageEmbed{d.Embed}, embeds...)
+	}
+
+	var (
+		msg *discordgo.Message
+		err error
+	)
+	prev := inv.nextPending()
+	// Files cannot be added and embeds cannot be removed by editing
+	if prev != nil && len(d.Files) == 0 && (len(embeds) != 0 || len(prev.Embeds) == 0) {
+		components := d.Components
+		if components == nil {
+			components = []discordgo.MessageComponent{}
+		}
+		msg, err = ctx.Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			Content: 			&d.Content,
+			Embeds: 			embeds,
+			Components: 		components,
+			AllowedMentions: 	d.AllowedMentions,
+			ID: 				prev.ID,
+			Channel: 			prev.ChannelID,
+		})
+	} else if prev != nil {
+		ctx.Session.ChannelMessageDelete(prev.ChannelID, prev.ID)
+	}
+
+	// Send a new message if there is nothing to edit or editing failed, like when it was deleted
+	if msg == nil {
+		msg, err = ctx.Session.ChannelMessageSendComplex(ctx.Message.ChannelID, d)
+	}
+
+	// The invoking message was deleted while the command was running
+	if err == nil && !inv.addResponse(msg) {
+		ctx.Session.ChannelMessageDelete(msg.ChannelID, msg.ID)
+	}
+	return msg, err
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestInvocationTrackerChanged(t *testing.T) {
+	var tr invocationTracker
+	now := time.Now()
+	edited := now.Add(time.Second)
+	later := now.Add(2 * time.Second)
+
+	tr.start(&discordgo.Message{ID: "1", Content: "!roll 6"}, now, time.Minute, 10)
+	tr.start(&discordgo.Message{ID: "2", Content: "!roll 6", EditedTimestamp: &edited}, now, time.Minute, 10)
+
+	tests := []struct {
+		name 	string
+		m 		*discordgo.Message
+		want 	bool
+	}{
+		{"edited", &discordgo.Message{ID: "1", Content: "!roll 20", EditedTimestamp: &edited}, true},
+		{"same content", &discordgo.Message{ID: "1", Content: "!roll 6", EditedTimestamp: &edited}, false},
+		{"pinned after an edit", &discordgo.Message{ID: "2", Content: "!roll 20", EditedTimestamp: &edited}, false},
+		{"edited again", &discordgo.Message{ID: "2", Content: "!roll 20", EditedTimestamp: &later}, true},
+		{"not tracked", &discordgo.Message{ID: "3", Content: "!roll 6", EditedTimestamp: &edited}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tr.changed(tt.m); got != tt.want {
+			t.Errorf("%s: changed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// A new run compares later updates with the edited message
+	tr.start(&discordgo.Message{ID: "1", Content: "!roll 20", EditedTimestamp: &edited}, now, time.Minute, 10)
+	if tr.changed(&discordgo.Message{ID: "1", Content: "!roll 20", EditedTimestamp: &edited}) {
+		t.Error("update of the re-run message is a change")
+	}
+}
+
+func TestInvocationTrackerEviction(t *testing.T) {
+	var tr invocationTracker
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		inv := tr.start(&discordgo.Message{ID: fmt.Sprint(i)}, now, time.Minute, 3)
+		inv.addResponse(&discordgo.Message{ID: fmt.Sprint("r", i)})
+	}
+	if len(tr.invocations) != 3 {
+		t.Fatalf("%d invocations, want 3", len(tr.invocations))
+	}
+	// The oldest ones are evicted first
+	for _, id := range []string{"0", "1"} {
+		if tr.take(id) != nil {
+			t.Errorf("invocation %s is not evicted", id)
+		}
+	}
+
+	// Re-running keeps the place of the message and makes its responses pending
+	inv := tr.start(&discordgo.Message{ID: "4"}, now, time.Minute, 3)
+	if len(tr.order) != 3 {
+		t.Errorf("order = %q, want 3 IDs", tr.order)
+	}
+	if p := inv.nextPending(); p == nil || p.ID != "r4" {
+		t.Errorf("pending response = %v, want r4", p)
+	}
+
+	// Removed invocations own nothing and refuse later responses
+	if msgs := tr.take("4").remove(); len(msgs) != 0 {
+		t.Errorf("removed invocation owns %d messages, want 0", len(msgs))
+	}
+	if inv.addResponse(&discordgo.Message{ID: "late"}) {
+		t.Error("removed invocation accepted a response")
+	}
+}
+
+func TestInvocationTakePending(t *testing.T) {
+	var tr invocationTracker
+	now := time.Now()
+
+	inv := tr.start(&discordgo.Message{ID: "1"}, now, time.Minute, 10)
+	for _, id := range []string{"r1", "r2", "r3"} {
+		inv.addResponse(&discordgo.Message{ID: id})
+	}
+
+	// The re-run reuses the first response, the others are left over
+	inv = tr.start(&discordgo.Message{ID: "1"}, now, time.Minute, 10)
+	inv.nextPending()
+	var ids []string
+	for _, msg := range inv.takePending() {
+		ids = append(ids, msg.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"r2", "r3"}) {
+		t.Errorf("left over responses = %q, want r2 and r3", ids)
+	}
+	if inv.nextPending() != nil {
+		t.Error("taken responses are still pending")
+	}
+}
+
+func TestInvocationTrackerExpiry(t *testing.T) {
+	var tr invocationTracker
+	now := time.Now()
+	ttl := 90 * time.Second
+
+	tr.start(&discordgo.Message{ID: "1"}, now, ttl, 10)
+	tr.start(&discordgo.Message{ID: "2"}, now.Add(30 * time.Second), ttl, 10)
+
+	// Swept at most once a minute
+	tr.start(&discordgo.Message{ID: "3"}, now.Add(50 * time.Second), ttl, 10)
+	if len(tr.invocations) != 3 {
+		t.Fatalf("%d invocations before the sweep, want 3", len(tr.invocations))
+	}
+
+	tr.start(&discordgo.Message{ID: "4"}, now.Add(2 * time.Minute), ttl, 10)
+	if tr.take("1") != nil || len(tr.invocations) != 3 || len(tr.order) != 3 {
+		t.Fatalf("order %q after the sweep, want 2, 3 and 4", tr.order)
+	}
+}
+
+func TestBulkDeleteChunks(t *testing.T) {
+	ids := make([]string, 250)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i)
+	}
+
+	tests := []struct {
+		guildID string
+		ids 	[]string
+		sizes 	[]int
+	}{
+		{"1", nil, []int{}},
+		{"1", ids[:1], []int{1}},
+		{"1", ids[:100], []int{100}},
+		{"1", ids, []int{100, 100, 50}},
+		{"", ids[:3], []int{1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		chunks := bulkDeleteChunks(tt.guildID, tt.ids)
+		sizes := []int{}
+		for _, chunk := range chunks {
+			sizes = append(sizes, len(chunk))
+		}
+		if !reflect.DeepEqual(sizes, tt.sizes) {
+			t.Errorf("bulkDeleteChunks(%q, %d ids) sizes = %v, want %v", tt.guildID, len(tt.ids), sizes, tt.sizes)
+		}
+	}
+}
@@ -2,17 +2,24 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// Match custom emojis <:name:id> and animated ones <a:name:id>
+var customEmojiRegex = regexp.MustCompile(`^<(a?):(\w+):(\d+)>$`)
+
 // Check for every element in array is a prefix for a string
+// Empty elements are skipped, they would match every string
 func slicePrefixesString(s string, arr []string) (index int, prefix string, exists bool) {
 	for i, val := range arr {
-		if strings.HasPrefix(s, val) {
+		if val != "" && strings.HasPrefix(s, val) {
 			return i, val, true
 		}
 	}
@@ -20,10 +27,96 @@ func slicePrefixesString(s string, arr []string) (index int, prefix string, exis
 	return -1, "", false
 }
 
-func parseContent(s string, prefix string) (commandName string, arguments []string) {
+func parseContent(s string, prefix string) (commandName string, arguments []string, err error) {
 	s = strings.TrimPrefix(s, prefix)
-	tokens := strings.Split(s, " ")
-	return tokens[0], tokens[1:]
+	tokens, err := tokenize(s)
+	if err != nil || len(tokens) == 0 {
+		return "", nil, err
+	}
+	return tokens[0], tokens[1:], nil
+}
+
+// Units of durations, d is a day
+var durationUnits = map[string]time.Duration{
+	"ns": 	time.Nanosecond,
+	"us": 	time.Microsecond,
+	"µs": 	time.Microsecond,
+	"ms": 	time.Millisecond,
+	"s": 	time.Second,
+	"m": 	time.Minute,
+	"h": 	time.Hour,
+	"d": 	24 * time.Hour,
+}
+
+// Match one number followed by its unit, longer units first so ms is not read as m
+var durationPartRegex = regexp.MustCompile(`(\d+(?:\.\d+)?|\.\d+)(ns|us|µs|ms|s|m|h|d)`)
+
+// Parse positive durations like time.ParseDuration, also accept days like 1d2h
+func parseDuration(str string) (time.Duration, error) {
+	invalid := fmt.Errorf("%s is not a duration", str)
+	if str == "" {
+		return 0, invalid
+	}
+
+	var total float64
+	end := 0
+	for _, m := range durationPartRegex.FindAllStringSubmatchIndex(str, -1) {
+		// Every character must belong to a part
+		if m[0] != end {
+			return 0, invalid
+		}
+		end = m[1]
+
+		value, err := strconv.ParseFloat(str[m[2]:m[3]], 64)
+		if err != nil {
+			return 0, invalid
+		}
+		total += value * float64(durationUnits[str[m[4]:m[5]]])
+	}
+	if end != len(str) {
+		return 0, invalid
+	}
+	if total >= math.MaxInt64 {
+		return 0, fmt.Errorf("%s is too long", str)
+	}
+	return time.Duration(total), nil
+}
+
+// Discord timestamp markup <t:seconds> or <t:seconds:style>
+var discordTimestampRegex = regexp.MustCompile(`^<t:(-?\d+)(?::[tTdDfFR])?>$`)
+
+// Layouts of ISO-8601 dates, dates without offset are in UTC
+// Offsets are written Z, +02:00 or +0200, fractional seconds are accepted after the seconds
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02 15:04Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Parse ISO-8601 dates, unix seconds and Discord timestamps
+func parseTimestamp(str string) (time.Time, error) {
+	if m := discordTimestampRegex.FindStringSubmatch(str); m != nil {
+		str = m[1]
+	}
+	if sec, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s is not a date", str)
 }
 
 func userConverter(s *discordgo.Session, str string) (*discordgo.User, error) {
@@ -58,6 +151,80 @@ func userConverter(s *discordgo.Session, str string) (*discordgo.User, error) {
 	return nil, fmt.Errorf("%s does not match any format for finding user (mention, id, name#tag)", str)
 }
 
+// Match user mentions <@id> and <@!id>
+var userMentionRegex = regexp.MustCompile(`^<@!?(\d+)>$`)
+
+// Find a member of the guild by mention, ID, name#tag, username or nickname
+// Names are matched exactly, then case insensitively, then by case insensitive prefix
+// Return an AmbiguousArgumentError if several members match at the same step
+func memberConverter(s *discordgo.Session, guildID string, str string) (*discordgo.Member, error) {
+	if guildID == "" {
+		return nil, ErrNotInGuild
+	}
+
+	id := str
+	if m := userMentionRegex.FindStringSubmatch(str); m != nil {
+		id = m[1]
+	}
+	if _, err := strconv.ParseUint(id, 10, 64); err == nil {
+		if member, err := s.State.Member(guildID, id); err == nil {
+			return member, nil
+		}
+		if member, err := s.GuildMember(guildID, id); err == nil {
+			return member, nil
+		}
+		// Names can be made of digits, mentions cannot
+		if id != str {
+			return nil, fmt.Errorf("cannot find that member")
+		}
+	}
+
+	// Members are only in the state with the guild members intent
+	g, err := s.State.Guild(guildID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find that member")
+	}
+	s.State.RLock()
+	members := append([]*discordgo.Member{}, g.Members...)
+	s.State.RUnlock()
+
+	lower := strings.ToLower(str)
+	steps := []func(name string) bool{
+		func(name string) bool { return name == str },
+		func(name string) bool { return strings.ToLower(name) == lower },
+		func(name string) bool { return strings.HasPrefix(strings.ToLower(name), lower) },
+	}
+	for i, match := range steps {
+		found := []*discordgo.Member{}
+		for _, member := range members {
+			if member.User == nil {
+				continue
+			}
+			// name#tag is only matched exactly
+			if (i == 0 && member.User.String() == str) || match(member.User.Username) || (member.Nick != "" && match(member.Nick)) {
+				found = append(found, member)
+			}
+		}
+
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		}
+		candidates := make([]string, len(found))
+		for j, member := range found {
+			candidates[j] = member.User.String()
+			if member.Nick != "" {
+				candidates[j] += " (" + member.Nick + ")"
+			}
+		}
+		sort.Strings(candidates)
+		return nil, &AmbiguousArgumentError{str, candidates}
+	}
+	return nil, fmt.Errorf("cannot find a member named %s", str)
+}
+
 func roleConverter(s *discordgo.Session, guildID string, str string) (*discordgo.Role, error) {
 	if strings.HasPrefix(str, "<") && strings.HasSuffix(str, ">") {		
 		rID := str[4:len(str)-1]
@@ -98,52 +265,67 @@ func channelConverter(s *discordgo.Session, guildID string, str string) (*discor
 	return nil, fmt.Errorf("%s does not match any format for finding channel (mention, id)", str)
 }
 
-func argumentsConverter(
-	s 		*discordgo.Session,
-	m 		*discordgo.Message,
-	arg 	string, 
-	Type 	MessageCommandParamType,
-) (res interface{}, err error) {
-
-	switch Type {
-	case MessageCommandParamTypeString:
-		return arg, nil
-	case MessageCommandParamTypeInteger:
-		if res, err := strconv.Atoi(arg); err != nil {
-			return nil, fmt.Errorf("%s is not integer", arg)
-		} else {
-			return res, nil
+// Match message links, the guild is @me in direct messages
+var messageLinkRegex = regexp.MustCompile(`^<?https?://(?:(?:www|ptb|canary)\.)?discord(?:app)?\.com/channels/(@me|\d+)/(\d+)/(\d+)>?$`)
+
+// Match channelID-messageID as copied from the client
+var channelMessageIDRegex = regexp.MustCompile(`^(\d+)-(\d+)$`)
+
+// Find a message by link, channelID-messageID or ID in the channel of m
+// The message must be in the server of m and readable by the author of m
+// In direct messages only the channel of m can be used
+func messageConverter(s *discordgo.Session, m *discordgo.Message, str string) (*discordgo.Message, error) {
+	channelID, messageID := m.ChannelID, str
+	if l := messageLinkRegex.FindStringSubmatch(str); l != nil {
+		guildID := l[1]
+		if guildID == "@me" {
+			guildID = ""
 		}
-	case MessageCommandParamTypeBoolean:
-		if res, err := strconv.ParseBool(arg); err != nil {
-			return nil, fmt.Errorf("cannot convert %s to boolean", arg)
-		} else {
-			return res, nil
+		if guildID != m.GuildID {
+			return nil, fmt.Errorf("that message does not belong to this server")
 		}
-	case MessageCommandParamTypeUser:
-		if u, err := userConverter(s, arg); err != nil {
-			return nil, err
-		} else {
-			return u, nil
+		channelID, messageID = l[2], l[3]
+	} else if ids := channelMessageIDRegex.FindStringSubmatch(str); ids != nil {
+		channelID, messageID = ids[1], ids[2]
+	} else if _, err := strconv.ParseUint(str, 10, 64); err != nil {
+		return nil, fmt.Errorf("%s does not match any format for finding message (link, channelID-messageID, id)", str)
+	}
+
+	if channelID != m.ChannelID {
+		if m.GuildID == "" {
+			return nil, fmt.Errorf("that message does not belong to this channel")
 		}
-	case MessageCommandParamTypeRole:
-		if r, err := roleConverter(s, m.GuildID, arg); err != nil {
-			return nil, err
-		} else {
-			return r, nil
+		c, err := s.State.Channel(channelID)
+		if err != nil {
+			if c, err = s.Channel(channelID); err != nil {
+				return nil, fmt.Errorf("cannot find that message")
+			}
 		}
-	case MessageCommandParamTypeChannel:
-		if c, err := channelConverter(s, m.GuildID, arg); err != nil {
-			return nil, err
-		} else {
-			return c, err
+		if c.GuildID != m.GuildID {
+			return nil, fmt.Errorf("that message does not belong to this server")
 		}
+		// Do not reveal messages of channels the user cannot read
+		missing, err := missingPermissions(s, channelID, m.Author.ID, discordgo.PermissionViewChannel|discordgo.PermissionReadMessageHistory)
+		if err != nil || missing != 0 {
+			return nil, fmt.Errorf("cannot find that message")
+		}
+	}
+
+	if msg, err := s.State.Message(channelID, messageID); err == nil {
+		return msg, nil
+	}
+	msg, err := s.ChannelMessage(channelID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find that message")
 	}
-	return nil, fmt.Errorf("cannot recognize the parameter type")
+	// Messages fetched from the API have no guild
+	msg.GuildID = m.GuildID
+	return msg, nil
 }
 
 func nextMessageCreateChannel(s *discordgo.Session) chan *discordgo.MessageCreate {
-	out := make(chan *discordgo.MessageCreate)
+	// Buffered so the handler does not block if nobody receives anymore
+	out := make(chan *discordgo.MessageCreate, 1)
 	s.AddHandlerOnce(func(_ *discordgo.Session, e *discordgo.MessageCreate) {
 		out <- e
 	})
@@ -151,7 +333,8 @@ func nextMessageCreateChannel(s *discordgo.Session) chan *discordgo.MessageCreat
 }
 
 func nextInteractionCreateChannel(s *discordgo.Session) chan *discordgo.InteractionCreate {
-	out := make(chan *discordgo.InteractionCreate)
+	// Buffered so the handler does not block if nobody receives anymore
+	out := make(chan *discordgo.InteractionCreate, 1)
 	s.AddHandlerOnce(func(_ *discordgo.Session, e *discordgo.InteractionCreate) {
 		out <- e
 	})
@@ -167,4 +350,15 @@ func toInteractionResponseData(msg *discordgo.MessageSend) *discordgo.Interactio
 		Files: msg.Files,
 		Components: msg.Components,
 	}
+}
+
+// Get the ID of the user who created the interaction, in a server or in direct messages
+func interactionUserID(i *discordgo.Interaction) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
 }
\ No newline at end of file
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in 		string
+		want 	time.Duration
+	}{
+		{"90s", 90 * time.Second},
+		{"1h30m", 90 * time.Minute},
+		{"1d2h", 26 * time.Hour},
+		{"1.5d", 36 * time.Hour},
+		{".5h", 30 * time.Minute},
+		{"250ms", 250 * time.Millisecond},
+		{"106751d", 106751 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got, err := parseDuration(tt.in); err != nil || got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "90", "-1h", "1x", "h", "1h 2m", "1.5.5h", "999999999999d", "9223372036854775807ns", "9223372036854775808ns", "106751d23h47m16.854775808s"} {
+		if got, err := parseDuration(in); err == nil {
+			t.Errorf("parseDuration(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2022, 1, 31, 18, 0, 0, 0, time.UTC)
+	for _, in := range []string{
+		"1643652000",
+		"<t:1643652000>",
+		"<t:1643652000:R>",
+		"2022-01-31T18:00:00Z",
+		"2022-01-31T19:00:00+01:00",
+		"2022-01-31T18:00:00.000Z",
+		"2022-01-31T18:00Z",
+		"2022-01-31T20:00+02:00",
+		"2022-01-31T20:00+0200",
+		"2022-01-31T20:00:00+0200",
+		"2022-01-31 18:00Z",
+		"2022-01-31 20:00+02:00",
+		"2022-01-31 20:00:00+02:00",
+		"2022-01-31T18:00:00",
+		"2022-01-31T18:00",
+		"2022-01-31 18:00:00.0",
+		"2022-01-31 18:00",
+	} {
+		if got, err := parseTimestamp(in); err != nil || !got.Equal(want) {
+			t.Errorf("parseTimestamp(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+
+	if got, err := parseTimestamp("2022-01-31"); err != nil || !got.Equal(time.Date(2022, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseTimestamp(\"2022-01-31\") = %v, %v", got, err)
+	}
+
+	for _, in := range []string{"", "tomorrow", "2022-01-31T18", "2022-13-01", "2022-01-31T18:00+2"} {
+		if got, err := parseTimestamp(in); err == nil {
+			t.Errorf("parseTimestamp(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+// Fail every request, the tests only use the state
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("offline")
+}
+
+// Session with an empty state that cannot reach the API
+func offlineSession(t *testing.T) *discordgo.Session {
+	s, err := discordgo.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.State = discordgo.NewState()
+	s.Client = &http.Client{Transport: offlineTransport{}}
+	return s
+}
+
+func TestMemberConverter(t *testing.T) {
+	s := offlineSession(t)
+	if err := s.State.GuildAdd(&discordgo.Guild{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range []*discordgo.Member{
+		{User: &discordgo.User{ID: "10", Username: "Alice", Discriminator: "0001"}},
+		{User: &discordgo.User{ID: "11", Username: "alice", Discriminator: "0002"}},
+		{User: &discordgo.User{ID: "12", Username: "Bob", Discriminator: "0003"}, Nick: "Bobby"},
+		{User: &discordgo.User{ID: "13", Username: "Bobcat", Discriminator: "0004"}},
+		{User: &discordgo.User{ID: "14", Username: "1234", Discriminator: "0005"}},
+	} {
+		member.GuildID = "1"
+		if err := s.State.MemberAdd(member); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		in 		string
+		want 	string
+	}{
+		{"<@!12>", "12"},
+		{"13", "13"},
+		{"alice#0002", "11"},
+		{"Alice", "10"},
+		{"Bob", "12"},
+		{"BOBCAT", "13"},
+		{"bobby", "12"},
+		{"bobc", "13"},
+		{"1234", "14"},
+	}
+	for _, tt := range tests {
+		member, err := memberConverter(s, "1", tt.in)
+		if err != nil || member.User.ID != tt.want {
+			t.Errorf("memberConverter(%q) = %v, %v, want %s", tt.in, member, err, tt.want)
+		}
+	}
+
+	ambiguous := []struct {
+		in 			string
+		candidates 	[]string
+	}{
+		{"ALICE", []string{"Alice#0001", "alice#0002"}},
+		{"bo", []string{"Bob#0003 (Bobby)", "Bobcat#0004"}},
+	}
+	for _, tt := range ambiguous {
+		var amb *AmbiguousArgumentError
+		_, err := memberConverter(s, "1", tt.in)
+		if !errors.As(err, &amb) || !reflect.DeepEqual(amb.Candidates, tt.candidates) {
+			t.Errorf("memberConverter(%q) error = %v, want candidates %q", tt.in, err, tt.candidates)
+		}
+	}
+
+	for _, in := range []string{"carol", "<@99>", "99"} {
+		if member, err := memberConverter(s, "1", in); err == nil {
+			t.Errorf("memberConverter(%q) = %v, want an error", in, member)
+		}
+	}
+	if _, err := memberConverter(s, "", "Alice"); !errors.Is(err, ErrNotInGuild) {
+		t.Errorf("error = %v outside a server, want ErrNotInGuild", err)
+	}
+}
+
+func TestMessageLinkRegex(t *testing.T) {
+	tests := []struct {
+		in 		string
+		want 	[]string
+	}{
+		{"https://discord.com/channels/1/2/3", []string{"1", "2", "3"}},
+		{"https://discord.com/channels/@me/2/3", []string{"@me", "2", "3"}},
+		{"<https://discord.com/channels/1/2/3>", []string{"1", "2", "3"}},
+		{"https://ptb.discord.com/channels/1/2/3", []string{"1", "2", "3"}},
+		{"https://canary.discordapp.com/channels/1/2/3", []string{"1", "2", "3"}},
+		{"http://www.discordapp.com/channels/@me/2/3", []string{"@me", "2", "3"}},
+		{"https://example.com/channels/1/2/3", nil},
+		{"https://discord.com/channels/1/2", nil},
+		{"https://discord.com/channels/1/2/3 extra", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		if l := messageLinkRegex.FindStringSubmatch(tt.in); l != nil {
+			got = l[1:]
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("link %q = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	ids := []struct {
+		in 		string
+		want 	[]string
+	}{
+		{"2-3", []string{"2", "3"}},
+		{"3", nil},
+		{"2-", nil},
+		{"a-3", nil},
+		{"2-3-4", nil},
+	}
+	for _, tt := range ids {
+		var got []string
+		if m := channelMessageIDRegex.FindStringSubmatch(tt.in); m != nil {
+			got = m[1:]
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("channelID-messageID %q = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMessageConverter(t *testing.T) {
+	s := offlineSession(t)
+	s.State.MaxMessageCount = 10
+	guilds := []*discordgo.Guild{
+		{
+			ID: 		"1",
+			Roles: 		[]*discordgo.Role{{ID: "1", Permissions: discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory}},
+			Channels: 	[]*discordgo.Channel{{ID: "2", GuildID: "1"}, {ID: "5", GuildID: "1"}},
+			Members: 	[]*discordgo.Member{{GuildID: "1", User: &discordgo.User{ID: "9"}}},
+		},
+		{ID: "7", Channels: []*discordgo.Channel{{ID: "8", GuildID: "7"}}},
+	}
+	for _, g := range guilds {
+		if err := s.State.GuildAdd(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.State.ChannelAdd(&discordgo.Channel{ID: "20", Type: discordgo.ChannelTypeDM}); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []*discordgo.Message{
+		{ID: "3", ChannelID: "2", GuildID: "1"},
+		{ID: "6", ChannelID: "5", GuildID: "1"},
+		{ID: "30", ChannelID: "8", GuildID: "7"},
+		{ID: "21", ChannelID: "20"},
+	} {
+		if err := s.State.MessageAdd(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inGuild := &discordgo.Message{ID: "4", ChannelID: "2", GuildID: "1", Author: &discordgo.User{ID: "9"}}
+	inDM := &discordgo.Message{ID: "22", ChannelID: "20", Author: &discordgo.User{ID: "9"}}
+	tests := []struct {
+		name 	string
+		m 		*discordgo.Message
+		in 		string
+		want 	string
+	}{
+		{"id", inGuild, "3", "3"},
+		{"link", inGuild, "https://discord.com/channels/1/2/3", "3"},
+		{"wrapped link to another channel", inGuild, "<https://ptb.discord.com/channels/1/5/6>", "6"},
+		{"channelID-messageID", inGuild, "5-6", "6"},
+		{"link in direct messages", inDM, "https://canary.discord.com/channels/@me/20/21", "21"},
+		{"id in direct messages", inDM, "21", "21"},
+	}
+	for _, tt := range tests {
+		msg, err := messageConverter(s, tt.m, tt.in)
+		if err != nil || msg.ID != tt.want {
+			t.Errorf("%s: messageConverter(%q) = %v, %v, want %s", tt.name, tt.in, msg, err, tt.want)
+		}
+	}
+
+	rejected := []struct {
+		name 	string
+		m 		*discordgo.Message
+		in 		string
+		want 	string
+	}{
+		{"link to another server", inGuild, "https://discord.com/channels/7/8/30", "that message does not belong to this server"},
+		{"direct message link in a server", inGuild, "https://discord.com/channels/@me/20/21", "that message does not belong to this server"},
+		{"channel of another server", inGuild, "8-30", "that message does not belong to this server"},
+		{"server link in direct messages", inDM, "https://discord.com/channels/1/2/3", "that message does not belong to this server"},
+		{"other channel in direct messages", inDM, "2-3", "that message does not belong to this channel"},
+		{"unknown message", inGuild, "99", "cannot find that message"},
+		{"not a message", inGuild, "hello", "hello does not match any format for finding message (link, channelID-messageID, id)"},
+	}
+	for _, tt := range rejected {
+		if msg, err := messageConverter(s, tt.m, tt.in); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: messageConverter(%q) = %v, %v, want %q", tt.name, tt.in, msg, err, tt.want)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"log"
 	"sync"
 	"time"
 
@@ -86,6 +88,10 @@ type WaitingList struct {
 
 	Event 	EventHandler
 
+	// Run Traverse on a bounded pool of workers, nil to run each one in a new goroutine
+	// Events dropped or rejected by the executor never reach the waiters
+	Executor *Executor
+
 	Begin 	*WaitingNode
 	End 	*WaitingNode
 	Len 	int
@@ -109,13 +115,13 @@ func (li *WaitingList) Add(node *WaitingNode) {
 }
 
 func (li *WaitingList) Traverse(event interface{}) {
+	li.Lock()
+	defer li.Unlock()
+
 	if li.Begin == nil || li.End == nil || li.Len == 0 {
 		return
 	}
 
-	li.Lock()
-	defer li.Unlock()
-
 	current := li.Begin
 
 	var prev *WaitingNode
@@ -153,253 +159,187 @@ func (li *WaitingList) Traverse(event interface{}) {
 	li.Begin, li.End, li.Len = begin, end, count
 }
 
+// Pass the event to the waiters in the background
+// Events the executor cannot take in time are dropped and logged
+func (li *WaitingList) dispatch(event interface{}) {
+	traverse := func() {
+		li.Traverse(event)
+	}
+	if li.Executor == nil {
+		go traverse()
+		return
+	}
+
+	if err := li.Executor.submitWithin(context.Background(), submitTimeout, traverse); err != nil {
+		log.Printf("%T dropped by the executor of the waiting list: %v", event, err)
+	}
+}
+
 func (li *WaitingList) AddHandler(s *discordgo.Session, event EventType) {
 	switch event {
 	case ChannelCreate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.ChannelCreate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case ChannelDelete:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.ChannelDelete) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case ChannelPinsUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.ChannelPinsUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case ChannelUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.ChannelUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildBanAdd:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildBanAdd) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildBanRemove:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildBanRemove) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildCreate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildCreate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildDelete:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildDelete) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildEmojisUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildEmojisUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildIntegrationsUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildIntegrationsUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildMemberAdd:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildMemberAdd) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildMemberRemove:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildMemberRemove) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildMemberUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildMemberUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildMembersChunk:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildMembersChunk) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildRoleCreate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildRoleCreate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildRoleDelete:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildRoleDelete) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildRoleUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildRoleUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case GuildUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.GuildUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case InteractionCreate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.InteractionCreate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case MessageAck:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.MessageAck) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case MessageCreate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.MessageCreate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case MessageDelete:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.MessageDelete) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case MessageDeleteBulk:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.MessageDeleteBulk) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case MessageReactionAdd:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.MessageReactionAdd) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case MessageReactionRemove:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.MessageReactionRemove) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case MessageReactionRemoveAll:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.MessageReactionRemoveAll) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case MessageUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.MessageUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case PresenceUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.PresenceUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case PresencesReplace:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.PresencesReplace) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case Ready:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.Ready) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case RelationshipAdd:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.RelationshipAdd) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case RelationshipRemove:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.RelationshipRemove) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case Resumed:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.Resumed) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case TypingStart:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.TypingStart) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case UserGuildSettingsUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.UserGuildSettingsUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case UserNoteUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.UserNoteUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case UserSettingsUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.UserSettingsUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case UserUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.UserUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case VoiceServerUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.VoiceServerUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case VoiceStateUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.VoiceStateUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	case WebhooksUpdate:
 		s.AddHandler(func(s *discordgo.Session, e *discordgo.WebhooksUpdate) {
-			go func() {
-				li.Traverse(e)
-			}()
+			li.dispatch(e)
 		})
 	}
 }
@@ -428,17 +368,43 @@ func (ew *EventWaiter) Set(event EventType, wt *WaitingList) {
 	ew.waiterMapping[event] = wt
 }
 
+// Wait for an event passing check, return nil on timeout
 func (ew *EventWaiter) WaitFor(event EventType, timeout time.Duration, check func(interface{}) bool) interface{} {
-	channel := make(chan interface{})
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	val, _ := ew.WaitForContext(ctx, event, check)
+	return val
+}
+
+// Wait for an event passing check until ctx is done, then return the error of ctx
+func (ew *EventWaiter) WaitForContext(ctx context.Context, event EventType, check func(interface{}) bool) (interface{}, error) {
+	// Buffered so Traverse never blocks, the channel is never closed because Traverse may still send
+	channel := make(chan interface{}, 1)
 	node := NewNode(channel, check)
-	ew.Get(event).Add(node)
+	li := ew.Get(event)
+	li.Add(node)
 	select {
 	case val := <- channel:
-		return val
-	case <- time.After(timeout):
+		return val, nil
+	case <- ctx.Done():
+		li.Lock()
 		node.Closed = true
-		close(channel)
-		return nil
+		li.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// Run the waiting lists of every event on the executor, see WaitingList.Executor
+// Call it before the session is opened
+// Use an executor of its own: if handlers waiting for events filled its workers,
+// the events they wait for would queue behind them and never be delivered
+func (ew *EventWaiter) UseExecutor(e *Executor) {
+	ew.RLock()
+	defer ew.RUnlock()
+
+	for _, li := range ew.waiterMapping {
+		li.Executor = e
 	}
 }
 
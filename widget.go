@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -31,10 +33,17 @@ type Widget struct {
 	// Check if using the default controller
 	DefaultCtrl		bool
 
+	// Rows shown below the default controller, like a select menu
+	ExtraComponents []discordgo.MessageComponent
+
 	// Users that have access to buttons
 	UserWhitelist	[]string
 
 	Running	bool
+
+	// Function invoked when a handler panics
+	// Use DefaultWidgetPanicHandler if nil
+	OnPanic 		func(w *Widget, i *discordgo.Interaction, p *RecoveredPanic)
 }
 
 func NewWidget(s *discordgo.Session, channelID string, msg *discordgo.MessageSend) *Widget {
@@ -60,7 +69,7 @@ func (w *Widget) IsUserAllowed(userID string) bool {
 }
 
 func (w *Widget) DefaultController(page int) []discordgo.MessageComponent {
-	return []discordgo.MessageComponent{
+	return append([]discordgo.MessageComponent{
 		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
 				discordgo.Button{
@@ -91,7 +100,7 @@ func (w *Widget) DefaultController(page int) []discordgo.MessageComponent {
 				},
 			},
 		},
-	}
+	}, w.ExtraComponents...)
 }
 
 func (w *Widget) InitializeDefaultController() {
@@ -101,6 +110,11 @@ func (w *Widget) InitializeDefaultController() {
 
 // Deploy the widget in channel w.ChannelID
 func (w *Widget) Deploy() error {
+	return w.DeployContext(context.Background())
+}
+
+// Same as Deploy, stop when ctx is done and return its error
+func (w *Widget) DeployContext(ctx context.Context) error {
 	if w.IsRunning() {
 		return ErrAlreadyRunning
 	}
@@ -134,6 +148,8 @@ func (w *Widget) Deploy() error {
 				return nil
 			case <-w.Close:
 				return nil
+			case <-ctx.Done():
+				return ctx.Err()
 			}
 		} else /* Navigation timeout not enabled */ {
 			select {
@@ -141,6 +157,8 @@ func (w *Widget) Deploy() error {
 				interaction = i.Interaction
 			case <-w.Close:
 				return nil
+			case <-ctx.Done():
+				return ctx.Err()
 			}
 		}
 
@@ -148,7 +166,7 @@ func (w *Widget) Deploy() error {
 		if interaction.Type != discordgo.InteractionMessageComponent {
 			continue
 		}
-		if interaction.MessageComponentData().ComponentType != discordgo.ButtonComponent {
+		if t := interaction.MessageComponentData().ComponentType; t != discordgo.ButtonComponent && t != discordgo.SelectMenuComponent {
 			continue
 		}
 		if interaction.Message.ID != w.Message.ID {
@@ -156,15 +174,18 @@ func (w *Widget) Deploy() error {
 		}
 
 		if h, ok := w.Handlers[interaction.MessageComponentData().CustomID]; ok {
-			if w.IsUserAllowed(interaction.Member.User.ID) {
-				go h(w, interaction)
+			if w.IsUserAllowed(interactionUserID(interaction)) {
+				go func(h WidgetHandler, i *discordgo.Interaction) {
+					defer w.recoverPanic(i)
+					h(w, i)
+				}(h, interaction)
 			}
 		}
 	}
 }
 
 // Handle adds a handler for the given emoji name
-//    action: The CustomID of button
+//    action: The CustomID of button or select menu
 //    handler  : handler function to call when the button is clicked
 //               func(*Widget, *discordgo.InteractionCreate)
 func (w *Widget) AddHandler(action string, handler WidgetHandler) {
@@ -178,6 +199,18 @@ func (w *Widget) AddHandler(action string, handler WidgetHandler) {
 //    userID : UserID to get message from
 //    timeout: How long to wait for the user's response
 func (w *Widget) QueryInput(prompt string, userID string, timeout time.Duration) (*discordgo.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	msg, err := w.QueryInputContext(ctx, prompt, userID)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, ErrTimeout
+	}
+	return msg, err
+}
+
+// Same as QueryInput, wait until ctx is done and return its error
+func (w *Widget) QueryInputContext(ctx context.Context, prompt string, userID string) (*discordgo.Message, error) {
 	msg, err := w.Session.ChannelMessageSend(w.ChannelID, "<@"+userID+">,  "+prompt)
 	if err != nil {
 		return nil, err
@@ -186,12 +219,6 @@ func (w *Widget) QueryInput(prompt string, userID string, timeout time.Duration)
 		w.Session.ChannelMessageDelete(msg.ChannelID, msg.ID)
 	}()
 
-	timeoutChan := make(chan int)
-	go func() {
-		time.Sleep(timeout)
-		timeoutChan <- 0
-	}()
-
 	for {
 		select {
 		case usermsg := <-nextMessageCreateChannel(w.Session):
@@ -200,8 +227,8 @@ func (w *Widget) QueryInput(prompt string, userID string, timeout time.Duration)
 			}
 			w.Session.ChannelMessageDelete(usermsg.ChannelID, usermsg.ID)
 			return usermsg.Message, nil
-		case <-timeoutChan:
-			return nil, ErrTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
 }